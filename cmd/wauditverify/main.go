@@ -0,0 +1,42 @@
+// wauditverify 校验wlog审计日志文件是否被篡改
+//
+// 用法：WLOG_AUDIT_KEY=<密钥> wauditverify [-expect-seq N] [-expect-mac HEX] audit.log
+// 传入上次校验时保存的序号或hmac，可以额外发现文件末尾被整行截断的情况
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/mundo-wang/wtool/wlog"
+)
+
+func main() {
+	expectSeq := flag.Uint64("expect-seq", 0, "审计文件中至少应包含的序号")
+	expectMAC := flag.String("expect-mac", "", "序号为expect-seq的那一行的hmac")
+	flag.Parse()
+	if flag.NArg() != 1 {
+		fmt.Fprintln(os.Stderr, "usage: wauditverify [-expect-seq N] [-expect-mac HEX] <audit-file>")
+		os.Exit(2)
+	}
+	key := os.Getenv("WLOG_AUDIT_KEY")
+	if key == "" {
+		fmt.Fprintln(os.Stderr, "WLOG_AUDIT_KEY is not set")
+		os.Exit(2)
+	}
+	result, err := wlog.VerifyAuditFile(flag.Arg(0), []byte(key))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	if result.LastSeq < *expectSeq {
+		fmt.Fprintf(os.Stderr, "audit file truncated: last seq %d, expected at least %d\n", result.LastSeq, *expectSeq)
+		os.Exit(1)
+	}
+	if *expectMAC != "" && result.LastSeq == *expectSeq && result.LastMAC != *expectMAC {
+		fmt.Fprintf(os.Stderr, "audit file tail mismatch: last hmac %s, expected %s\n", result.LastMAC, *expectMAC)
+		os.Exit(1)
+	}
+	fmt.Printf("ok: %d lines, last seq %d, last hmac %s\n", result.Lines, result.LastSeq, result.LastMAC)
+}
@@ -0,0 +1,203 @@
+package whttp
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mundo-wang/wtool/wtoken"
+)
+
+// AuthProvider 为请求提供认证信息
+type AuthProvider interface {
+	// Apply 为请求设置认证信息，如Authorization请求头
+	Apply(req *http.Request) error
+	// Invalidate 服务端返回401时调用，使req所用的凭证失效，下次Apply时重新获取
+	Invalidate(req *http.Request)
+}
+
+// authInterceptor 发送前通过AuthProvider设置认证信息，服务端返回401时使凭证失效并重新获取，透明地重试一次
+// 请求体无法重新读取时不重试，直接返回401响应
+func authInterceptor(provider AuthProvider) Interceptor {
+	return func(next RoundTrip) RoundTrip {
+		return func(req *http.Request) (*http.Response, error) {
+			retryReq := req.Clone(req.Context()) // Apply会修改请求头，预先保留一份原始请求用于重试
+			if err := provider.Apply(req); err != nil {
+				return nil, err
+			}
+			resp, err := next(req)
+			if err != nil || resp.StatusCode != http.StatusUnauthorized {
+				return resp, err
+			}
+			if req.Body != nil && req.Body != http.NoBody {
+				if req.GetBody == nil {
+					return resp, nil
+				}
+				if retryReq.Body, err = req.GetBody(); err != nil {
+					return resp, nil
+				}
+			}
+			_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+			_ = resp.Body.Close()
+			provider.Invalidate(req)
+			if err = provider.Apply(retryReq); err != nil {
+				return nil, err
+			}
+			return next(retryReq)
+		}
+	}
+}
+
+// ClientCredentialsConfig OAuth2客户端凭证模式（client_credentials）的配置
+type ClientCredentialsConfig struct {
+	TokenURL      string            // 获取access_token的地址
+	ClientID      string            // 客户端ID
+	ClientSecret  string            // 客户端密钥
+	Scopes        []string          // 申请的权限范围
+	AuthInBody    bool              // 为true时client_id和client_secret放在请求体中，否则使用HTTP Basic认证
+	RefreshBefore time.Duration     // 在token过期前多久重新获取，默认30秒，最多为token有效期的一半
+	Store         wtoken.TokenStore // 缓存token的存储，默认为wtoken.Store
+	Timeout       time.Duration     // 获取token的超时时间，默认使用Client的Timeout，Client也未设置时为10秒
+	// 获取token使用的Client，默认为DefaultClient，只使用其连接池、TLS、代理和重试配置，
+	// 不使用该Client的Auth、Signer、信封模式、拦截器、熔断器和限流器
+	Client *Client
+}
+
+// ClientCredentials 通过OAuth2客户端凭证模式获取和刷新access_token的AuthProvider
+// token缓存在TokenStore中，并发请求在token过期时只会触发一次获取
+type ClientCredentials struct {
+	cfg      ClientCredentialsConfig
+	storeKey string
+	mu       sync.Mutex
+	call     *tokenCall // 正在进行中的获取token请求
+}
+
+type tokenCall struct {
+	done  chan struct{}
+	token string
+	err   error
+}
+
+type tokenResponse struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	ExpiresIn   int64  `json:"expires_in"`
+}
+
+func NewClientCredentials(cfg ClientCredentialsConfig) *ClientCredentials {
+	if cfg.RefreshBefore <= 0 {
+		cfg.RefreshBefore = 30 * time.Second
+	}
+	if cfg.Store == nil {
+		cfg.Store = wtoken.Store
+	}
+	if cfg.Client == nil {
+		cfg.Client = DefaultClient
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = cfg.Client.cfg.Timeout
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 10 * time.Second
+	}
+	storeKey := strings.Join([]string{"whttp:oauth2", cfg.TokenURL, cfg.ClientID, strings.Join(cfg.Scopes, " ")}, "|")
+	return &ClientCredentials{cfg: cfg, storeKey: storeKey}
+}
+
+// Apply 设置Authorization: Bearer <access_token>请求头，缓存中没有可用token时先获取
+func (c *ClientCredentials) Apply(req *http.Request) error {
+	authorization, err := c.Token(req.Context())
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", authorization)
+	return nil
+}
+
+// Invalidate 仅当req使用的token仍是当前缓存的token时才使其失效，避免并发收到401时重复获取
+func (c *ClientCredentials) Invalidate(req *http.Request) {
+	current, ok := c.cfg.Store.RetrieveToken(c.storeKey)
+	if ok && current == req.Header.Get("Authorization") {
+		c.cfg.Store.SaveToken(c.storeKey, "", -time.Second)
+	}
+}
+
+// Token 返回完整的Authorization请求头取值，如Bearer xxx
+func (c *ClientCredentials) Token(ctx context.Context) (string, error) {
+	if token, ok := c.cfg.Store.RetrieveToken(c.storeKey); ok && token != "" {
+		return token, nil
+	}
+	c.mu.Lock()
+	call := c.call
+	if call == nil {
+		call = &tokenCall{done: make(chan struct{})}
+		c.call = call
+		// 获取结果由所有等待者共享，不能因为发起者的请求被取消而失败，这里只保留上下文中的值（如traceId），
+		// 在后台Goroutine中获取，发起者和其他等待者一样在自己的上下文结束时返回
+		go func() {
+			call.token, call.err = c.fetch(context.WithoutCancel(ctx))
+			c.mu.Lock()
+			c.call = nil
+			c.mu.Unlock()
+			close(call.done)
+		}()
+	}
+	c.mu.Unlock()
+	select {
+	case <-call.done:
+		return call.token, call.err
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+}
+
+// tokenRequest 创建获取token的请求，token接口是标准的OAuth2接口，不能套用业务请求的信封、签名和认证，
+// 也不能经过业务拦截器和熔断器，否则token接口的失败会计入业务服务的熔断统计
+func tokenRequest(client *Client) HttpClient[tokenResponse] {
+	request := Post[tokenResponse](client).(*httpClient[tokenResponse])
+	request.envelope, request.auth, request.signer, request.interceptors = false, nil, nil, nil
+	request.direct = true
+	return request
+}
+
+func (c *ClientCredentials) fetch(ctx context.Context) (string, error) {
+	form := url.Values{"grant_type": {"client_credentials"}}
+	if len(c.cfg.Scopes) > 0 {
+		form.Set("scope", strings.Join(c.cfg.Scopes, " "))
+	}
+	request := tokenRequest(c.cfg.Client).WithContext(withoutClientTrace(ctx)).WithBaseURL(c.cfg.TokenURL).WithTimeout(c.cfg.Timeout)
+	if c.cfg.AuthInBody {
+		form.Set("client_id", c.cfg.ClientID)
+		form.Set("client_secret", c.cfg.ClientSecret)
+	} else {
+		basicReq := &http.Request{Header: http.Header{}}
+		basicReq.SetBasicAuth(url.QueryEscape(c.cfg.ClientID), url.QueryEscape(c.cfg.ClientSecret))
+		request = request.WithHeader("Authorization", basicReq.Header.Get("Authorization"))
+	}
+	resp, err := request.WithFormBody(form).WithHeader("Accept", "application/json").Send()
+	if err != nil {
+		return "", err
+	}
+	tokenResp := resp.GetRespData()
+	if tokenResp.AccessToken == "" {
+		return "", errors.New("oauth2 token response has no access_token")
+	}
+	tokenType := tokenResp.TokenType
+	if tokenType == "" || strings.EqualFold(tokenType, "bearer") {
+		tokenType = "Bearer"
+	}
+	authorization := tokenType + " " + tokenResp.AccessToken
+	// 提前RefreshBefore过期，保证使用中的token不会在请求途中失效；有效期很短时最多提前一半，保证token一定会被缓存
+	expiresIn := time.Duration(tokenResp.ExpiresIn) * time.Second
+	if tokenResp.ExpiresIn <= 0 {
+		expiresIn = time.Hour // 服务端未返回有效期时按1小时处理
+	}
+	refreshBefore := min(c.cfg.RefreshBefore, expiresIn/2)
+	c.cfg.Store.SaveToken(c.storeKey, authorization, expiresIn-refreshBefore)
+	return authorization, nil
+}
@@ -0,0 +1,143 @@
+package whttp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/mundo-wang/wtool/wtoken"
+)
+
+// tokenServer 每次调用签发一个新的token（t1、t2……），delay不为nil时等待其返回后再响应
+func tokenServer(t *testing.T, expiresIn int, delay chan struct{}) (string, *atomic.Int32) {
+	t.Helper()
+	var calls atomic.Int32
+	srv := newJSONServer(t, func(w http.ResponseWriter, r *http.Request) {
+		n := calls.Add(1)
+		if delay != nil {
+			<-delay
+		}
+		if user, pass, ok := r.BasicAuth(); !ok || user != "id" || pass != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = fmt.Fprintf(w, `{"access_token":"t%d","token_type":"bearer","expires_in":%d}`, n, expiresIn)
+	})
+	return srv.URL, &calls
+}
+
+func newTestCredentials(tokenURL string) *ClientCredentials {
+	return NewClientCredentials(ClientCredentialsConfig{
+		TokenURL:     tokenURL,
+		ClientID:     "id",
+		ClientSecret: "secret",
+		Store:        wtoken.NewTokenStore(),
+	})
+}
+
+func TestClientCredentialsSingleFlight(t *testing.T) {
+	delay := make(chan struct{})
+	tokenURL, tokenCalls := tokenServer(t, 3600, delay)
+	api := newJSONServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer t1" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		okHandler(w, r)
+	})
+	auth := newTestCredentials(tokenURL)
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 10)
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := NewGet[map[string]any]().WithBaseURL(api.URL).WithAuth(auth).Send()
+			errs <- err
+		}()
+	}
+	time.Sleep(50 * time.Millisecond)
+	close(delay)
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := tokenCalls.Load(); n != 1 {
+		t.Fatalf("token endpoint called %d times, want 1", n)
+	}
+}
+
+func TestClientCredentialsRetriesOn401(t *testing.T) {
+	tokenURL, tokenCalls := tokenServer(t, 3600, nil)
+	var apiCalls atomic.Int32
+	api := newJSONServer(t, func(w http.ResponseWriter, r *http.Request) {
+		apiCalls.Add(1)
+		// 第一个token被服务端吊销，只接受重新获取的token
+		if r.Header.Get("Authorization") != "Bearer t2" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		okHandler(w, r)
+	})
+	auth := newTestCredentials(tokenURL)
+	if _, err := NewPost[map[string]any]().WithBaseURL(api.URL).WithAuth(auth).WithJsonBody(map[string]int{"a": 1}).Send(); err != nil {
+		t.Fatal(err)
+	}
+	if tokenCalls.Load() != 2 || apiCalls.Load() != 2 {
+		t.Fatalf("token calls %d, api calls %d, want 2 and 2", tokenCalls.Load(), apiCalls.Load())
+	}
+	// 新token已经缓存，之后的请求不再获取
+	if _, err := NewGet[map[string]any]().WithBaseURL(api.URL).WithAuth(auth).Send(); err != nil {
+		t.Fatal(err)
+	}
+	if tokenCalls.Load() != 2 {
+		t.Fatalf("token calls %d after cache, want 2", tokenCalls.Load())
+	}
+}
+
+func TestClientCredentialsLeaderHonoursContext(t *testing.T) {
+	delay := make(chan struct{})
+	tokenURL, _ := tokenServer(t, 3600, delay)
+	t.Cleanup(func() { close(delay) }) // 先于关闭服务端执行，释放阻塞中的请求
+	api := newJSONServer(t, okHandler)
+	auth := newTestCredentials(tokenURL)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err := NewGet[map[string]any]().WithContext(ctx).WithBaseURL(api.URL).WithAuth(auth).Send()
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected deadline exceeded, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("request returned after %v, should not wait for the token endpoint", elapsed)
+	}
+}
+
+func TestClientCredentialsCachesShortLivedToken(t *testing.T) {
+	// 有效期20秒小于默认的RefreshBefore（30秒），仍然需要缓存
+	tokenURL, tokenCalls := tokenServer(t, 20, nil)
+	auth := newTestCredentials(tokenURL)
+	for i := 0; i < 3; i++ {
+		token, err := auth.Token(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if token != "Bearer t1" {
+			t.Fatalf("token %q, want Bearer t1", token)
+		}
+	}
+	if n := tokenCalls.Load(); n != 1 {
+		t.Fatalf("token endpoint called %d times, want 1", n)
+	}
+}
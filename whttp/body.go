@@ -0,0 +1,145 @@
+package whttp
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/url"
+	"os"
+
+	"github.com/google/go-querystring/query"
+)
+
+// bodyFunc 每次请求（包括重试和重定向）都会调用一次，返回一个从头读取的新请求体
+type bodyFunc func() (io.Reader, error)
+
+func bytesBody(data []byte) bodyFunc {
+	return func() (io.Reader, error) {
+		return bytes.NewReader(data), nil
+	}
+}
+
+// formValues 将url.Values、map[string]string或带url标签的结构体转换为url.Values
+func formValues(body interface{}) (url.Values, error) {
+	switch v := body.(type) {
+	case url.Values:
+		return v, nil
+	case map[string]string:
+		values := url.Values{}
+		for key, value := range v {
+			values.Set(key, value)
+		}
+		return values, nil
+	default:
+		return query.Values(body)
+	}
+}
+
+// formPart multipart/form-data中的一个部分，open为nil时表示普通字段
+type formPart struct {
+	field    string
+	value    string
+	fileName string
+	open     func() (io.ReadCloser, error)
+	oneShot  bool // 文件来源只能读取一次，包含该部分的请求体不能重试
+}
+
+// readerSource 把调用方传入的io.Reader包装为可多次打开的文件来源
+// 实现了io.Seeker的Reader每次打开时回到初始位置，因此可以重试；否则只能被读取一次
+func readerSource(reader io.Reader) func() (io.ReadCloser, error) {
+	seeker, ok := reader.(io.Seeker)
+	var start int64
+	if ok {
+		start, _ = seeker.Seek(0, io.SeekCurrent)
+	}
+	used := false
+	return func() (io.ReadCloser, error) {
+		if ok {
+			if _, err := seeker.Seek(start, io.SeekStart); err != nil {
+				return nil, err
+			}
+			return io.NopCloser(reader), nil
+		}
+		if used {
+			return nil, errors.New("multipart file reader can not be replayed, use an io.Seeker or WithFormFilePath")
+		}
+		used = true
+		return io.NopCloser(reader), nil
+	}
+}
+
+// replayable 判断multipart请求体能否再次发送
+func replayable(parts []formPart) bool {
+	for _, part := range parts {
+		if part.oneShot {
+			return false
+		}
+	}
+	return true
+}
+
+func fileSource(filePath string) func() (io.ReadCloser, error) {
+	return func() (io.ReadCloser, error) {
+		return os.Open(filePath)
+	}
+}
+
+// multipartBody 通过io.Pipe边读取文件边编码，文件内容不会整体加载到内存
+// 发送失败时http.Client会关闭请求体，写入端随之返回错误，后台Goroutine不会泄漏
+func multipartBody(parts []formPart, boundary string) bodyFunc {
+	return func() (io.Reader, error) {
+		files := make([]io.ReadCloser, len(parts))
+		for i, part := range parts {
+			if part.open == nil {
+				continue
+			}
+			file, err := part.open()
+			if err != nil {
+				closeAll(files)
+				return nil, fmt.Errorf("open multipart file %s: %w", part.fileName, err)
+			}
+			files[i] = file
+		}
+		pr, pw := io.Pipe()
+		go func() {
+			defer closeAll(files)
+			writer := multipart.NewWriter(pw)
+			_ = writer.SetBoundary(boundary)
+			err := writeParts(writer, parts, files)
+			if err == nil {
+				err = writer.Close()
+			}
+			_ = pw.CloseWithError(err)
+		}()
+		return pr, nil
+	}
+}
+
+func writeParts(writer *multipart.Writer, parts []formPart, files []io.ReadCloser) error {
+	for i, part := range parts {
+		if files[i] == nil {
+			if err := writer.WriteField(part.field, part.value); err != nil {
+				return err
+			}
+			continue
+		}
+		partWriter, err := writer.CreateFormFile(part.field, part.fileName)
+		if err != nil {
+			return err
+		}
+		if _, err = io.Copy(partWriter, files[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func closeAll(files []io.ReadCloser) {
+	for _, file := range files {
+		if file != nil {
+			_ = file.Close()
+		}
+	}
+}
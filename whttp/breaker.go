@@ -0,0 +1,258 @@
+package whttp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/mundo-wang/wtool/wlog"
+)
+
+// BreakerState 熔断器状态
+type BreakerState int
+
+const (
+	BreakerClosed   BreakerState = iota // 关闭状态，请求正常通过
+	BreakerOpen                         // 打开状态，请求直接失败
+	BreakerHalfOpen                     // 半开状态，放行少量探测请求，全部成功后关闭，任一失败则重新打开
+)
+
+func (s BreakerState) String() string {
+	switch s {
+	case BreakerClosed:
+		return "closed"
+	case BreakerOpen:
+		return "open"
+	case BreakerHalfOpen:
+		return "half-open"
+	default:
+		return "unknown"
+	}
+}
+
+// ErrCircuitOpen 熔断器处于打开状态时请求直接失败，可以通过errors.Is判断
+var ErrCircuitOpen = errors.New("circuit breaker is open")
+
+// CircuitOpenError 熔断器打开时返回的错误，Key为熔断的维度（默认为目标主机）
+type CircuitOpenError struct {
+	Key   string
+	State BreakerState
+}
+
+func (e *CircuitOpenError) Error() string {
+	return fmt.Sprintf("circuit breaker is %s for %s", e.State, e.Key)
+}
+
+func (e *CircuitOpenError) Is(target error) bool {
+	return target == ErrCircuitOpen
+}
+
+// BreakerConfig 熔断器配置，连续失败次数和失败率两个条件满足任意一个即熔断，未设置的字段使用默认值
+type BreakerConfig struct {
+	KeyFunc             func(req *http.Request) string            // 熔断的维度，默认按目标主机（host:port）
+	ConsecutiveFailures int                                       // 连续失败多少次后熔断，默认5
+	FailureRate         float64                                   // 统计窗口内失败率达到该值后熔断，取值(0, 1]，0表示不按失败率熔断
+	MinRequests         int                                       // 按失败率熔断时，统计窗口内至少需要的请求数，默认20
+	Window              time.Duration                             // 失败率的统计窗口，默认60秒
+	OpenTimeout         time.Duration                             // 打开状态持续多久后进入半开状态，默认30秒
+	HalfOpenRequests    int                                       // 半开状态放行的探测请求数，默认1
+	IsFailure           func(resp *http.Response, err error) bool // 判断请求是否失败，默认请求出错或状态码为5xx时视为失败
+	OnStateChange       func(key string, from, to BreakerState)   // 状态变化时的回调，可以使用LogBreakerStateChange
+}
+
+// CircuitBreaker 按Key隔离状态的熔断器，同一个熔断器可以在多个Client之间共享
+type CircuitBreaker struct {
+	cfg     BreakerConfig
+	mu      sync.Mutex
+	entries map[string]*breakerEntry
+}
+
+type breakerEntry struct {
+	state           BreakerState
+	consecutive     int       // 连续失败次数
+	windowStart     time.Time // 当前统计窗口的开始时间
+	total           int       // 当前统计窗口内的请求数
+	failures        int       // 当前统计窗口内的失败数
+	openedAt        time.Time
+	halfOpenPending int // 半开状态下已放行但未返回的探测请求数
+	halfOpenSuccess int // 半开状态下已成功的探测请求数
+}
+
+func NewCircuitBreaker(cfg BreakerConfig) *CircuitBreaker {
+	if cfg.KeyFunc == nil {
+		cfg.KeyFunc = func(req *http.Request) string { return req.URL.Host }
+	}
+	if cfg.ConsecutiveFailures <= 0 {
+		cfg.ConsecutiveFailures = 5
+	}
+	if cfg.MinRequests <= 0 {
+		cfg.MinRequests = 20
+	}
+	if cfg.Window <= 0 {
+		cfg.Window = time.Minute
+	}
+	if cfg.OpenTimeout <= 0 {
+		cfg.OpenTimeout = 30 * time.Second
+	}
+	if cfg.HalfOpenRequests <= 0 {
+		cfg.HalfOpenRequests = 1
+	}
+	if cfg.IsFailure == nil {
+		cfg.IsFailure = func(resp *http.Response, err error) bool {
+			return err != nil || resp.StatusCode >= 500
+		}
+	}
+	return &CircuitBreaker{cfg: cfg, entries: make(map[string]*breakerEntry)}
+}
+
+// LogBreakerStateChange 通过wlog记录熔断器状态变化，可直接用作BreakerConfig.OnStateChange
+func LogBreakerStateChange(key string, from, to BreakerState) {
+	entry := wlog.Msg("circuit breaker state changed").
+		Field("key", key).Field("from", from.String()).Field("to", to.String())
+	if to == BreakerOpen {
+		entry.LevelWarn()
+	} else {
+		entry.LevelInfo()
+	}
+}
+
+// State 返回指定Key当前的熔断器状态
+func (b *CircuitBreaker) State(key string) BreakerState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	entry, ok := b.entries[key]
+	if !ok {
+		return BreakerClosed
+	}
+	if entry.state == BreakerOpen && time.Since(entry.openedAt) >= b.cfg.OpenTimeout {
+		return BreakerHalfOpen
+	}
+	return entry.state
+}
+
+// Interceptor 返回熔断拦截器，每次请求（包括重试）单独计数，熔断时返回*CircuitOpenError且不会被重试
+func (b *CircuitBreaker) Interceptor() Interceptor {
+	return func(next RoundTrip) RoundTrip {
+		return func(req *http.Request) (*http.Response, error) {
+			key := b.cfg.KeyFunc(req)
+			if err := b.allow(key); err != nil {
+				closeRequestBody(req)
+				return nil, err
+			}
+			resp, err := next(req)
+			// 调用方主动取消的请求不能说明下游的健康状况，不参与统计
+			if err != nil && errors.Is(err, context.Canceled) {
+				b.release(key)
+				return resp, err
+			}
+			b.record(key, b.cfg.IsFailure(resp, err))
+			return resp, err
+		}
+	}
+}
+
+func (b *CircuitBreaker) allow(key string) error {
+	b.mu.Lock()
+	entry := b.entry(key)
+	var changed bool
+	switch entry.state {
+	case BreakerOpen:
+		if time.Since(entry.openedAt) < b.cfg.OpenTimeout {
+			b.mu.Unlock()
+			return &CircuitOpenError{Key: key, State: BreakerOpen}
+		}
+		entry.state = BreakerHalfOpen
+		entry.halfOpenPending, entry.halfOpenSuccess = 0, 0
+		changed = true
+		fallthrough
+	case BreakerHalfOpen:
+		if entry.halfOpenPending+entry.halfOpenSuccess >= b.cfg.HalfOpenRequests {
+			b.mu.Unlock()
+			b.notify(key, changed, BreakerOpen, BreakerHalfOpen)
+			return &CircuitOpenError{Key: key, State: BreakerHalfOpen}
+		}
+		entry.halfOpenPending++
+	}
+	b.mu.Unlock()
+	b.notify(key, changed, BreakerOpen, BreakerHalfOpen)
+	return nil
+}
+
+// release 放弃本次请求的统计，半开状态下归还探测名额
+func (b *CircuitBreaker) release(key string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	entry := b.entry(key)
+	if entry.state == BreakerHalfOpen && entry.halfOpenPending > 0 {
+		entry.halfOpenPending--
+	}
+}
+
+func (b *CircuitBreaker) record(key string, failed bool) {
+	b.mu.Lock()
+	entry := b.entry(key)
+	from := entry.state
+	now := time.Now()
+	switch entry.state {
+	case BreakerHalfOpen:
+		if entry.halfOpenPending > 0 {
+			entry.halfOpenPending--
+		}
+		if failed {
+			b.open(entry, now)
+		} else if entry.halfOpenSuccess++; entry.halfOpenSuccess >= b.cfg.HalfOpenRequests {
+			b.reset(entry, BreakerClosed, now)
+		}
+	case BreakerClosed:
+		if now.Sub(entry.windowStart) >= b.cfg.Window {
+			entry.windowStart, entry.total, entry.failures = now, 0, 0
+		}
+		entry.total++
+		if failed {
+			entry.failures++
+			entry.consecutive++
+		} else {
+			entry.consecutive = 0
+		}
+		rateTripped := b.cfg.FailureRate > 0 && entry.total >= b.cfg.MinRequests &&
+			float64(entry.failures)/float64(entry.total) >= b.cfg.FailureRate
+		if entry.consecutive >= b.cfg.ConsecutiveFailures || rateTripped {
+			b.open(entry, now)
+		}
+	}
+	to := entry.state
+	b.mu.Unlock()
+	b.notify(key, from != to, from, to)
+}
+
+func (b *CircuitBreaker) open(entry *breakerEntry, now time.Time) {
+	b.reset(entry, BreakerOpen, now)
+	entry.openedAt = now
+}
+
+func (b *CircuitBreaker) reset(entry *breakerEntry, state BreakerState, now time.Time) {
+	entry.state = state
+	entry.consecutive = 0
+	entry.windowStart, entry.total, entry.failures = now, 0, 0
+	entry.halfOpenPending, entry.halfOpenSuccess = 0, 0
+}
+
+// 调用方需持有b.mu
+func (b *CircuitBreaker) entry(key string) *breakerEntry {
+	entry, ok := b.entries[key]
+	if !ok {
+		entry = &breakerEntry{state: BreakerClosed, windowStart: time.Now()}
+		b.entries[key] = entry
+	}
+	return entry
+}
+
+// notify 在锁外调用状态变化回调，避免回调中再次访问熔断器造成死锁
+func (b *CircuitBreaker) notify(key string, changed bool, from, to BreakerState) {
+	if changed && b.cfg.OnStateChange != nil {
+		b.cfg.OnStateChange(key, from, to)
+	}
+}
@@ -0,0 +1,153 @@
+package whttp
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// stateRecorder 记录熔断器的状态变化
+type stateRecorder struct {
+	mu          sync.Mutex
+	transitions []string
+}
+
+func (r *stateRecorder) record(_ string, from, to BreakerState) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.transitions = append(r.transitions, from.String()+"->"+to.String())
+}
+
+func (r *stateRecorder) String() string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return strings.Join(r.transitions, ",")
+}
+
+func TestCircuitBreakerStateMachine(t *testing.T) {
+	var failing atomic.Bool
+	var calls atomic.Int32
+	failing.Store(true)
+	srv := newJSONServer(t, func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		if failing.Load() {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		okHandler(w, r)
+	})
+	recorder := &stateRecorder{}
+	breaker := NewCircuitBreaker(BreakerConfig{
+		ConsecutiveFailures: 2,
+		OpenTimeout:         100 * time.Millisecond,
+		OnStateChange:       recorder.record,
+	})
+	client := NewClient(ClientConfig{CircuitBreaker: breaker})
+	send := func() error {
+		_, err := Get[map[string]any](client).WithBaseURL(srv.URL).Send()
+		return err
+	}
+	key := strings.TrimPrefix(srv.URL, "http://")
+
+	for i := 0; i < 2; i++ {
+		if err := send(); err == nil || errors.Is(err, ErrCircuitOpen) {
+			t.Fatalf("request %d: expected 500 error, got %v", i, err)
+		}
+	}
+	if state := breaker.State(key); state != BreakerOpen {
+		t.Fatalf("state %s after consecutive failures, want open", state)
+	}
+	err := send()
+	var openErr *CircuitOpenError
+	if !errors.As(err, &openErr) || openErr.Key != key {
+		t.Fatalf("expected CircuitOpenError, got %v", err)
+	}
+	if calls.Load() != 2 {
+		t.Fatalf("open breaker should not reach the server, calls %d", calls.Load())
+	}
+
+	// 半开状态探测失败时重新打开
+	time.Sleep(120 * time.Millisecond)
+	if state := breaker.State(key); state != BreakerHalfOpen {
+		t.Fatalf("state %s after open timeout, want half-open", state)
+	}
+	if err = send(); err == nil || errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("expected probe to fail with 500, got %v", err)
+	}
+	if state := breaker.State(key); state != BreakerOpen {
+		t.Fatalf("state %s after failed probe, want open", state)
+	}
+
+	// 半开状态探测成功时关闭
+	time.Sleep(120 * time.Millisecond)
+	failing.Store(false)
+	if err = send(); err != nil {
+		t.Fatal(err)
+	}
+	if state := breaker.State(key); state != BreakerClosed {
+		t.Fatalf("state %s after successful probe, want closed", state)
+	}
+	want := "closed->open,open->half-open,half-open->open,open->half-open,half-open->closed"
+	if got := recorder.String(); got != want {
+		t.Fatalf("transitions %s, want %s", got, want)
+	}
+}
+
+func TestCircuitBreakerFailureRate(t *testing.T) {
+	var calls atomic.Int32
+	srv := newJSONServer(t, func(w http.ResponseWriter, r *http.Request) {
+		// 成功和失败交替，连续失败次数不会达到阈值
+		if calls.Add(1)%2 == 0 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		okHandler(w, r)
+	})
+	breaker := NewCircuitBreaker(BreakerConfig{ConsecutiveFailures: 100, FailureRate: 0.5, MinRequests: 4, OpenTimeout: time.Minute})
+	client := NewClient(ClientConfig{CircuitBreaker: breaker})
+	for i := 0; i < 4; i++ {
+		_, _ = Get[map[string]any](client).WithBaseURL(srv.URL).Send()
+	}
+	if _, err := Get[map[string]any](client).WithBaseURL(srv.URL).Send(); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("expected breaker to open at 50%% failure rate, got %v", err)
+	}
+}
+
+func TestCircuitBreakerHalfOpenLimitsProbes(t *testing.T) {
+	release := make(chan struct{})
+	var failing atomic.Bool
+	failing.Store(true)
+	srv := newJSONServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if failing.Load() {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		<-release
+		okHandler(w, r)
+	})
+	breaker := NewCircuitBreaker(BreakerConfig{ConsecutiveFailures: 1, OpenTimeout: 50 * time.Millisecond})
+	client := NewClient(ClientConfig{CircuitBreaker: breaker})
+	_, _ = Get[map[string]any](client).WithBaseURL(srv.URL).Send()
+	time.Sleep(70 * time.Millisecond)
+	failing.Store(false)
+
+	probe := make(chan error, 1)
+	go func() {
+		_, err := Get[map[string]any](client).WithBaseURL(srv.URL).Send()
+		probe <- err
+	}()
+	time.Sleep(30 * time.Millisecond)
+	_, err := Get[map[string]any](client).WithBaseURL(srv.URL).Send()
+	var openErr *CircuitOpenError
+	if !errors.As(err, &openErr) || openErr.State != BreakerHalfOpen {
+		t.Fatalf("second request during probe should be rejected, got %v", err)
+	}
+	close(release)
+	if err = <-probe; err != nil {
+		t.Fatal(err)
+	}
+}
@@ -1,44 +1,95 @@
 package whttp
 
 import (
+	"context"
 	"errors"
+	"hash"
+	"io"
 	"net"
 	"net/http"
 	"net/url"
+	"reflect"
+	"strings"
+	"sync"
 	"time"
+
+	"github.com/mundo-wang/wtool/wlog"
 )
 
 type HttpClient[T any] interface {
+	WithContext(ctx context.Context) HttpClient[T]
 	WithBaseURL(baseURL string) HttpClient[T]
 	WithTimeout(timeout time.Duration) HttpClient[T]
 	WithRetry(retryCount int, retryDelay, maxRetryDelay time.Duration) HttpClient[T]
+	WithRetryPolicy(policy RetryPolicy) HttpClient[T]
+	WithAttemptHook(hook func(attempt *Attempt)) HttpClient[T]
 	WithJsonBody(body interface{}) HttpClient[T]
+	WithBody(codec Codec, body interface{}) HttpClient[T]
+	WithCompressedBody(encoding string) HttpClient[T]
+	WithResponseCodec(codec Codec) HttpClient[T]
+	WithFormBody(body interface{}) HttpClient[T]
+	WithFormField(key, value string) HttpClient[T]
+	WithFormFile(field, fileName string, reader io.Reader) HttpClient[T]
+	WithFormFilePath(field, filePath string) HttpClient[T]
 	WithPathParam(args ...string) HttpClient[T]
 	WithQueryParam(key, value string) HttpClient[T]
 	WithQueryParamByMap(params map[string]string) HttpClient[T]
 	WithQueryParamByStruct(params interface{}) HttpClient[T]
+	WithEnvelope() HttpClient[T]
+	WithErrorType(errorType interface{}) HttpClient[T]
 	WithHeader(key, value string) HttpClient[T]
 	WithHeaderByMap(headers map[string]string) HttpClient[T]
+	WithAuth(provider AuthProvider) HttpClient[T]
+	WithSigner(signer Signer) HttpClient[T]
+	Use(interceptors ...Interceptor) HttpClient[T]
+	WithProgress(progress func(written, total int64)) HttpClient[T]
+	WithMaxSize(maxSize int64) HttpClient[T]
+	WithChecksum(h hash.Hash, expected string) HttpClient[T]
 	Send() (ResponseWrapper[T], error)
+	SendTo(w io.Writer) (ResponseWrapper[T], error)
+	Download(filePath string) (ResponseWrapper[T], error)
 }
 
 type httpClient[T any] struct {
+	ctx           context.Context
 	baseURL       string
 	method        string
 	fullURL       string
 	queryParams   url.Values
-	jsonBody      []byte
+	body          bodyFunc   // 请求体，为nil表示没有请求体
+	compression   string     // 请求体的压缩编码，为空表示不压缩
+	formParts     []formPart // multipart/form-data请求体的各个部分
+	boundary      string     // multipart/form-data请求体的分隔符
 	headers       map[string]string
-	client        *http.Client
+	client        *Client
+	timeout       time.Duration // 单次请求的超时时间，0表示不限制
 	err           error
 	retryCount    int           // 最大重试次数
 	retryDelay    time.Duration // 首次重试延迟
 	maxRetryDelay time.Duration // 最大重试延迟
+	retryPolicy   RetryPolicy   // 判断是否需要重试
+	attemptHook   func(attempt *Attempt)
+	interceptors  []Interceptor              // Client级别和请求级别的拦截器，按由外到内的顺序排列
+	progress      func(written, total int64) // SendTo和Download的进度回调
+	maxSize       int64                      // SendTo和Download允许的最大响应体字节数
+	checksum      hash.Hash                  // SendTo和Download的校验和算法
+	expectedSum   string                     // 期望的十六进制校验和
+	errorType     reflect.Type               // 非2xx响应体对应的错误类型
+	envelope      bool                       // 是否按wresp的{code, message, data}结构解析响应体
+	respCodec     Codec                      // 解码响应体的编解码器，为nil表示根据Content-Type选择
+	auth          AuthProvider               // 认证信息提供者，为nil表示不需要认证
+	signer        Signer                     // 请求签名器，为nil表示不签名
+	direct        bool                       // 为true时不经过Client的熔断器和限流器，直接发送请求
 }
 
 type ResponseWrapper[T any] interface {
 	GetRespBytes() []byte
 	GetRespData() T
+	GetAttempts() int
+	GetStatusCode() int
+	GetFinalURL() string
+	GetLatency() time.Duration
+	GetTiming() Timing
 	GetRespHeader(key string) string
 	GetRespHeaderMulti(key string) []string
 }
@@ -47,45 +98,253 @@ type responseWrapper[T any] struct {
 	respHeaders http.Header
 	respBytes   []byte
 	respData    T
+	statusCode  int
+	finalURL    string
+	attempts    int
+	latency     time.Duration
+	timing      Timing
+}
+
+// ClientConfig 可复用Client的配置，未设置的字段使用默认值
+type ClientConfig struct {
+	BaseURL             string            // 默认基础URL，请求中通过WithBaseURL传入相对路径时拼接在其后
+	Headers             map[string]string // 每个请求默认携带的请求头，请求中设置的同名请求头优先
+	Timeout             time.Duration     // 单次请求的默认超时时间，0表示不限制超时
+	RetryCount          int               // 默认最大重试次数，0表示不重试
+	RetryDelay          time.Duration     // 默认首次重试延迟
+	MaxRetryDelay       time.Duration     // 默认最大重试延迟
+	RetryPolicy         RetryPolicy       // 默认重试策略，为nil时使用DefaultRetryPolicy
+	AttemptHook         func(*Attempt)    // 每次请求（包括重试）结束后调用的钩子函数
+	Interceptors        []Interceptor     // 拦截器，也可以在创建后通过Client.Use添加
+	Envelope            bool              // 是否默认开启信封模式，适用于调用返回wresp标准结构的内部服务
+	CircuitBreaker      *CircuitBreaker   // 熔断器，为nil表示不熔断
+	RateLimiter         *RateLimiter      // 限流器，为nil表示不限流
+	Auth                AuthProvider      // 认证信息提供者，如NewClientCredentials创建的OAuth2客户端凭证
+	Signer              Signer            // 请求签名器，如NewHMACSigner、NewSigV4Signer，为nil表示不签名
+	MaxIdleConns        int               // 全局最大空闲连接数，默认100
+	MaxIdleConnsPerHost int               // 每个目标主机最大空闲连接数，默认2
+	MaxConnsPerHost     int               // 每个目标主机最大连接数，默认0表示不限制
+	IdleConnTimeout     time.Duration     // 空闲连接最大存活时间，超过则关闭连接，默认90秒
+	TLS                 *TLSConfig        // TLS配置，包括根证书、mTLS客户端证书和最低TLS版本，为nil时使用系统默认配置
+	Proxy               *ProxyConfig      // 代理配置，为nil时读取HTTP_PROXY、HTTPS_PROXY、NO_PROXY环境变量
+	MaxDecompressedSize int64             // 解压后响应体的最大字节数，防止压缩炸弹，默认128MB，负数表示不限制
+	// 自定义底层Transport，如whttptest的录制回放Transport，设置后上面的连接池、TLS和代理配置不生效，
+	// 也不再主动添加Accept-Encoding，是否压缩由自定义Transport决定，响应体仍受MaxDecompressedSize限制
+	Transport http.RoundTripper
+}
+
+// Client 可复用的HTTP客户端，由它派生的所有请求共享同一个连接池
+// Client应当在进程内长期持有并复用，而不是每次请求都重新创建
+type Client struct {
+	cfg          ClientConfig
+	client       *http.Client
+	mu           sync.RWMutex
+	interceptors []Interceptor
+	err          error // 创建时的配置错误，如证书文件不存在，由该Client派生的请求发送时返回该错误
+	acceptGzip   bool  // 使用内置Transport时由do声明接受gzip并负责解压
+}
+
+// DefaultClient 进程级的默认Client，NewGet、NewPost等函数创建的请求都由它派生
+var DefaultClient = NewClient(ClientConfig{})
+
+// NewClient 根据配置创建Client，配置中的默认值可被单个请求的With系列方法覆盖
+// 配置错误（如证书文件不存在）时输出错误日志，由该Client派生的请求发送时返回该错误，需要在启动时发现配置错误请使用NewClientE
+func NewClient(cfg ClientConfig) *Client {
+	client, err := NewClientE(cfg)
+	if err != nil {
+		wlog.Msg("whttp client config is invalid, requests will fail").Err(err).LevelError()
+	}
+	return client
+}
+
+// NewClientE 与NewClient相同，同时返回配置错误，返回的Client不为nil
+func NewClientE(cfg ClientConfig) (*Client, error) {
+	if cfg.MaxIdleConns <= 0 {
+		cfg.MaxIdleConns = 100
+	}
+	if cfg.MaxIdleConnsPerHost <= 0 {
+		cfg.MaxIdleConnsPerHost = 2
+	}
+	if cfg.IdleConnTimeout <= 0 {
+		cfg.IdleConnTimeout = 90 * time.Second
+	}
+	if cfg.MaxDecompressedSize == 0 {
+		cfg.MaxDecompressedSize = 128 << 20
+	}
+	headers := make(map[string]string, len(cfg.Headers))
+	for key, value := range cfg.Headers {
+		headers[key] = value
+	}
+	cfg.Headers = headers
+	if cfg.RetryPolicy == nil {
+		cfg.RetryPolicy = DefaultRetryPolicy
+	}
+	var err error
+	transport := cfg.Transport
+	acceptGzip := transport == nil
+	if transport == nil {
+		// 基于http.DefaultTransport克隆，保留代理、拨号超时、HTTP/2等默认设置
+		defaultTransport := http.DefaultTransport.(*http.Transport).Clone()
+		defaultTransport.MaxIdleConns = cfg.MaxIdleConns
+		defaultTransport.MaxIdleConnsPerHost = cfg.MaxIdleConnsPerHost
+		defaultTransport.MaxConnsPerHost = cfg.MaxConnsPerHost
+		defaultTransport.IdleConnTimeout = cfg.IdleConnTimeout
+		// 由do统一添加Accept-Encoding并解压，保证所有响应都受MaxDecompressedSize限制
+		defaultTransport.DisableCompression = true
+		err = configureTransport(defaultTransport, cfg)
+		transport = defaultTransport
+	}
+	return &Client{
+		cfg:          cfg,
+		client:       &http.Client{Transport: transport},
+		interceptors: append([]Interceptor(nil), cfg.Interceptors...),
+		err:          err,
+		acceptGzip:   acceptGzip,
+	}, err
+}
+
+// configureTransport 在默认Transport上应用TLS和代理配置
+func configureTransport(transport *http.Transport, cfg ClientConfig) error {
+	if cfg.TLS != nil {
+		tlsConfig, err := buildTLSConfig(cfg.TLS)
+		if err != nil {
+			return err
+		}
+		transport.TLSClientConfig = tlsConfig
+	}
+	if cfg.Proxy != nil {
+		proxy, err := proxyFunc(cfg.Proxy)
+		if err != nil {
+			return err
+		}
+		transport.Proxy = proxy
+	}
+	return nil
+}
+
+// roundTrip 实际发送请求的RoundTrip，配置了熔断器和限流器时包装在所有拦截器的内层
+// 熔断器位于限流器外层，熔断期间的请求直接失败，不会占用限流名额；签名位于最内层，在等待限流名额之后才生成时间戳
+func (c *Client) roundTrip(signer Signer) RoundTrip {
+	roundTrip := RoundTrip(c.do)
+	if signer != nil {
+		roundTrip = signInterceptor(signer)(roundTrip)
+	}
+	if c.cfg.RateLimiter != nil {
+		roundTrip = c.cfg.RateLimiter.Interceptor()(roundTrip)
+	}
+	if c.cfg.CircuitBreaker != nil {
+		roundTrip = c.cfg.CircuitBreaker.Interceptor()(roundTrip)
+	}
+	return roundTrip
+}
+
+// do 发送请求并透明地解压响应体，拦截器和调用方看到的都是解压后的内容
+func (c *Client) do(req *http.Request) (*http.Response, error) {
+	// 与http.Transport相同，Range请求不声明gzip，避免拿到压缩后内容的片段
+	if c.acceptGzip && req.Header.Get("Accept-Encoding") == "" && req.Header.Get("Range") == "" && req.Method != http.MethodHead {
+		req.Header.Set("Accept-Encoding", EncodingGzip)
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if err = decompressResponse(resp, c.cfg.MaxDecompressedSize); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// authRoundTrip 在roundTrip外层加上认证，认证位于用户拦截器的内层，收到401后的重试对拦截器透明，并且会重新签名
+func (c *Client) authRoundTrip(provider AuthProvider, signer Signer) RoundTrip {
+	roundTrip := c.roundTrip(signer)
+	if provider != nil {
+		roundTrip = authInterceptor(provider)(roundTrip)
+	}
+	return roundTrip
+}
+
+// CloseIdleConnections 关闭连接池中的空闲连接，一般在进程退出或不再使用该Client时调用
+func (c *Client) CloseIdleConnections() {
+	c.client.CloseIdleConnections()
 }
 
 func NewGet[T any]() HttpClient[T] {
-	return newHttpClient[T](http.MethodGet)
+	return Get[T](DefaultClient)
 }
 
 func NewPost[T any]() HttpClient[T] {
-	return newHttpClient[T](http.MethodPost)
+	return Post[T](DefaultClient)
 }
 
 func NewPut[T any]() HttpClient[T] {
-	return newHttpClient[T](http.MethodPut)
+	return Put[T](DefaultClient)
 }
 
 func NewPatch[T any]() HttpClient[T] {
-	return newHttpClient[T](http.MethodPatch)
+	return Patch[T](DefaultClient)
 }
 
 func NewDelete[T any]() HttpClient[T] {
-	return newHttpClient[T](http.MethodDelete)
+	return Delete[T](DefaultClient)
+}
+
+func Get[T any](c *Client) HttpClient[T] {
+	return newHttpClient[T](c, http.MethodGet)
+}
+
+func Post[T any](c *Client) HttpClient[T] {
+	return newHttpClient[T](c, http.MethodPost)
+}
+
+func Put[T any](c *Client) HttpClient[T] {
+	return newHttpClient[T](c, http.MethodPut)
+}
+
+func Patch[T any](c *Client) HttpClient[T] {
+	return newHttpClient[T](c, http.MethodPatch)
 }
 
-// newHttpClient 初始化HttpClient，配置默认的连接池参数和空请求头/查询参数
-func newHttpClient[T any](method string) HttpClient[T] {
-	// 这里设置的都是默认值，可根据后端服务场景进行修改
-	transport := &http.Transport{
-		MaxIdleConns:        100,              // 全局最大空闲连接数
-		MaxIdleConnsPerHost: 2,                // 每个目标主机最大空闲连接数
-		IdleConnTimeout:     90 * time.Second, // 空闲连接最大存活时间，超过则关闭连接
+func Delete[T any](c *Client) HttpClient[T] {
+	return newHttpClient[T](c, http.MethodDelete)
+}
+
+// newHttpClient 从Client派生一个请求，继承Client的基础URL、默认请求头、超时时间和重试策略
+func newHttpClient[T any](c *Client, method string) HttpClient[T] {
+	if c == nil {
+		c = DefaultClient
 	}
-	client := &http.Client{
-		Transport: transport,
+	headers := make(map[string]string, len(c.cfg.Headers))
+	for key, value := range c.cfg.Headers {
+		headers[key] = value
 	}
-	return &httpClient[T]{
-		method:      method,
-		queryParams: url.Values{},
-		headers:     make(map[string]string),
-		client:      client,
+	cli := &httpClient[T]{
+		ctx:          context.Background(),
+		baseURL:      c.cfg.BaseURL,
+		method:       method,
+		queryParams:  url.Values{},
+		headers:      headers,
+		client:       c,
+		timeout:      c.cfg.Timeout,
+		retryPolicy:  c.cfg.RetryPolicy,
+		attemptHook:  c.cfg.AttemptHook,
+		interceptors: c.snapshotInterceptors(),
+		envelope:     c.cfg.Envelope,
+		auth:         c.cfg.Auth,
+		signer:       c.cfg.Signer,
+		err:          c.err,
 	}
+	if c.cfg.RetryCount > 0 {
+		cli.WithRetry(c.cfg.RetryCount, c.cfg.RetryDelay, c.cfg.MaxRetryDelay)
+	}
+	return cli
+}
+
+// joinBaseURL 请求URL不带协议时视为相对路径，拼接在Client配置的BaseURL之后
+func joinBaseURL(clientBaseURL, baseURL string) string {
+	if clientBaseURL == "" || strings.Contains(baseURL, "://") {
+		return baseURL
+	}
+	return strings.TrimRight(clientBaseURL, "/") + "/" + strings.TrimLeft(baseURL, "/")
 }
 
 // isTimeoutError 判断给定错误是否为网络超时错误
@@ -98,4 +357,28 @@ func isTimeoutError(err error) bool {
 		return netErr.Timeout()
 	}
 	return false
-}
\ No newline at end of file
+}
+
+// sleepWithContext 等待指定时长，期间上下文结束时提前返回上下文的错误
+func sleepWithContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// cancelOnClose 在响应体关闭时释放单次请求的超时上下文
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (c *cancelOnClose) Close() error {
+	err := c.ReadCloser.Close()
+	c.cancel()
+	return err
+}
@@ -0,0 +1,140 @@
+package whttp
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"mime"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/ugorji/go/codec"
+	"google.golang.org/protobuf/proto"
+	"gopkg.in/yaml.v3"
+)
+
+// Codec 请求体和响应体的编解码器
+type Codec interface {
+	ContentType() string // 编码后请求体的Content-Type
+	Marshal(v interface{}) ([]byte, error)
+	Unmarshal(data []byte, v interface{}) error
+}
+
+var (
+	JSONCodec     Codec = jsonCodec{}
+	XMLCodec      Codec = xmlCodec{}
+	ProtobufCodec Codec = protobufCodec{}
+	YAMLCodec     Codec = yamlCodec{}
+	MsgpackCodec  Codec = &msgpackCodec{handle: &codec.MsgpackHandle{}}
+)
+
+var (
+	codecMu sync.RWMutex
+	codecs  = map[string]Codec{
+		"application/json":       JSONCodec,
+		"text/json":              JSONCodec,
+		"application/xml":        XMLCodec,
+		"text/xml":               XMLCodec,
+		"application/x-protobuf": ProtobufCodec,
+		"application/protobuf":   ProtobufCodec,
+		"application/yaml":       YAMLCodec,
+		"application/x-yaml":     YAMLCodec,
+		"text/yaml":              YAMLCodec,
+		"application/msgpack":    MsgpackCodec,
+		"application/x-msgpack":  MsgpackCodec,
+	}
+)
+
+// RegisterCodec 注册自定义编解码器，响应的Content-Type为contentTypes之一时使用它解码，已注册的同名类型会被覆盖
+func RegisterCodec(c Codec, contentTypes ...string) {
+	codecMu.Lock()
+	defer codecMu.Unlock()
+	for _, contentType := range contentTypes {
+		codecs[strings.ToLower(contentType)] = c
+	}
+}
+
+// CodecFor 根据Content-Type查找编解码器，忽略charset等参数，未注册的+json、+xml后缀类型分别使用JSON和XML
+func CodecFor(contentType string) (Codec, bool) {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return nil, false
+	}
+	codecMu.RLock()
+	c, ok := codecs[mediaType]
+	codecMu.RUnlock()
+	if ok {
+		return c, true
+	}
+	switch {
+	case strings.HasSuffix(mediaType, "+json"):
+		return JSONCodec, true
+	case strings.HasSuffix(mediaType, "+xml"):
+		return XMLCodec, true
+	}
+	return nil, false
+}
+
+type jsonCodec struct{}
+
+func (jsonCodec) ContentType() string                        { return "application/json" }
+func (jsonCodec) Marshal(v interface{}) ([]byte, error)      { return json.Marshal(v) }
+func (jsonCodec) Unmarshal(data []byte, v interface{}) error { return json.Unmarshal(data, v) }
+
+type xmlCodec struct{}
+
+func (xmlCodec) ContentType() string                        { return "application/xml" }
+func (xmlCodec) Marshal(v interface{}) ([]byte, error)      { return xml.Marshal(v) }
+func (xmlCodec) Unmarshal(data []byte, v interface{}) error { return xml.Unmarshal(data, v) }
+
+type yamlCodec struct{}
+
+func (yamlCodec) ContentType() string                        { return "application/yaml" }
+func (yamlCodec) Marshal(v interface{}) ([]byte, error)      { return yaml.Marshal(v) }
+func (yamlCodec) Unmarshal(data []byte, v interface{}) error { return yaml.Unmarshal(data, v) }
+
+type msgpackCodec struct {
+	handle *codec.MsgpackHandle
+}
+
+func (c *msgpackCodec) ContentType() string { return "application/msgpack" }
+
+func (c *msgpackCodec) Marshal(v interface{}) ([]byte, error) {
+	var data []byte
+	err := codec.NewEncoderBytes(&data, c.handle).Encode(v)
+	return data, err
+}
+
+func (c *msgpackCodec) Unmarshal(data []byte, v interface{}) error {
+	return codec.NewDecoderBytes(data, c.handle).Decode(v)
+}
+
+// protobufCodec 要求对象实现proto.Message，解码时T为*pb.Xxx这类指针类型，指针为nil时自动创建
+type protobufCodec struct{}
+
+func (protobufCodec) ContentType() string { return "application/x-protobuf" }
+
+func (protobufCodec) Marshal(v interface{}) ([]byte, error) {
+	msg, ok := v.(proto.Message)
+	if !ok {
+		return nil, fmt.Errorf("protobuf codec: %T does not implement proto.Message", v)
+	}
+	return proto.Marshal(msg)
+}
+
+func (protobufCodec) Unmarshal(data []byte, v interface{}) error {
+	rv := reflect.ValueOf(v)
+	// decodeData传入的是**pb.Xxx，需要取出内层指针
+	if rv.Kind() == reflect.Ptr && rv.Elem().Kind() == reflect.Ptr {
+		if rv.Elem().IsNil() {
+			rv.Elem().Set(reflect.New(rv.Elem().Type().Elem()))
+		}
+		v = rv.Elem().Interface()
+	}
+	msg, ok := v.(proto.Message)
+	if !ok {
+		return fmt.Errorf("protobuf codec: %T does not implement proto.Message", v)
+	}
+	return proto.Unmarshal(data, msg)
+}
@@ -0,0 +1,132 @@
+package whttp
+
+import (
+	"bufio"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// 请求体压缩和响应体解压支持的Content-Encoding
+const (
+	EncodingGzip    = "gzip"
+	EncodingDeflate = "deflate"
+)
+
+// ErrDecompressedTooLarge 解压后的响应体超过ClientConfig.MaxDecompressedSize
+var ErrDecompressedTooLarge = errors.New("decompressed response body exceeds max size")
+
+// compressBody 通过io.Pipe边读取边压缩，大请求体不会在内存中同时保留压缩前后两份数据
+func compressBody(body bodyFunc, encoding string) bodyFunc {
+	return func() (io.Reader, error) {
+		src, err := body()
+		if err != nil {
+			return nil, err
+		}
+		pr, pw := io.Pipe()
+		go func() {
+			if closer, ok := src.(io.Closer); ok {
+				defer closer.Close()
+			}
+			var zw io.WriteCloser
+			if encoding == EncodingDeflate {
+				zw = zlib.NewWriter(pw)
+			} else {
+				zw = gzip.NewWriter(pw)
+			}
+			_, err := io.Copy(zw, src)
+			if closeErr := zw.Close(); err == nil {
+				err = closeErr
+			}
+			_ = pw.CloseWithError(err)
+		}()
+		return pr, nil
+	}
+}
+
+// decompressResponse 解压gzip和deflate编码的响应体，解压后移除Content-Encoding和Content-Length
+// 默认Transport关闭了自动解压，由这里统一处理；自定义Transport已经自动解压时只限制解压后的大小
+func decompressResponse(resp *http.Response, maxSize int64) error {
+	if resp.Uncompressed {
+		if maxSize > 0 && resp.Body != nil && resp.Body != http.NoBody {
+			resp.Body = &decompressedBody{reader: io.NopCloser(resp.Body), body: resp.Body, remaining: maxSize, limited: true}
+		}
+		return nil
+	}
+	encoding := strings.ToLower(strings.TrimSpace(resp.Header.Get("Content-Encoding")))
+	if encoding != EncodingGzip && encoding != EncodingDeflate {
+		return nil
+	}
+	if resp.Body == nil || resp.Body == http.NoBody {
+		return nil
+	}
+	body := resp.Body
+	var reader io.ReadCloser
+	if encoding == EncodingGzip {
+		gr, err := gzip.NewReader(body)
+		if err != nil {
+			_ = body.Close()
+			return fmt.Errorf("decompress gzip response: %w", err)
+		}
+		reader = gr
+	} else {
+		reader = newDeflateReader(body)
+	}
+	resp.Body = &decompressedBody{reader: reader, body: body, remaining: maxSize, limited: maxSize > 0}
+	resp.Header.Del("Content-Encoding")
+	resp.Header.Del("Content-Length")
+	resp.ContentLength = -1
+	resp.Uncompressed = true
+	return nil
+}
+
+// newDeflateReader HTTP的deflate编码按规范应为zlib格式，但也有服务端直接返回原始deflate数据，这里根据zlib头部判断
+func newDeflateReader(body io.Reader) io.ReadCloser {
+	buffered := bufio.NewReader(body)
+	header, err := buffered.Peek(2)
+	if err == nil && header[0]&0x0f == 8 && (uint16(header[0])<<8|uint16(header[1]))%31 == 0 {
+		if zr, err := zlib.NewReader(buffered); err == nil {
+			return zr
+		}
+	}
+	return flate.NewReader(buffered)
+}
+
+// decompressedBody 读取解压后的数据，超过上限时返回ErrDecompressedTooLarge，关闭时同时关闭解压器和原始响应体
+type decompressedBody struct {
+	reader    io.ReadCloser
+	body      io.ReadCloser
+	remaining int64
+	limited   bool
+}
+
+func (d *decompressedBody) Read(p []byte) (int, error) {
+	if !d.limited {
+		return d.reader.Read(p)
+	}
+	if d.remaining <= 0 {
+		// 恰好达到上限时再尝试读取1个字节，确认数据是否真的超出
+		var probe [1]byte
+		n, err := d.reader.Read(probe[:])
+		if n > 0 {
+			return 0, ErrDecompressedTooLarge
+		}
+		return 0, err
+	}
+	if int64(len(p)) > d.remaining {
+		p = p[:d.remaining]
+	}
+	n, err := d.reader.Read(p)
+	d.remaining -= int64(n)
+	return n, err
+}
+
+func (d *decompressedBody) Close() error {
+	_ = d.reader.Close()
+	return d.body.Close()
+}
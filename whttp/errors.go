@@ -0,0 +1,144 @@
+package whttp
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+)
+
+// HTTPError 响应状态码不是2xx时返回的错误，可以通过errors.As获取状态码、响应头和响应体
+//
+//	var httpErr *whttp.HTTPError
+//	if errors.As(err, &httpErr) && httpErr.StatusCode == http.StatusNotFound {
+//		...
+//	}
+type HTTPError struct {
+	StatusCode int
+	Method     string
+	URL        string         // 最终请求的URL，发生重定向时为重定向后的地址
+	Header     http.Header    // 响应头
+	Body       []byte         // 原始响应体
+	ParsedBody map[string]any // 响应体为JSON对象时的解析结果，否则为nil
+	ErrorBody  any            // 通过WithErrorType声明了错误类型时，响应体反序列化得到的对象指针，解析失败时为nil
+}
+
+func (e *HTTPError) Error() string {
+	msg := fmt.Sprintf("http status code not 2xx, is %d", e.StatusCode)
+	if e.ParsedBody != nil {
+		msg = fmt.Sprintf("%s, body: %v", msg, e.ParsedBody)
+	}
+	return msg
+}
+
+// Unwrap 错误类型实现了error接口时返回ErrorBody，使errors.As可以直接取出该错误类型
+func (e *HTTPError) Unwrap() error {
+	if err, ok := e.ErrorBody.(error); ok {
+		return err
+	}
+	return nil
+}
+
+// ErrorBodyAs 从err中取出通过WithErrorType声明的错误响应体，err不是*HTTPError或类型不匹配时返回false
+//
+//	resp, err := whttp.NewGet[User]().WithErrorType(&ApiError{}).WithBaseURL(url).Send()
+//	if apiErr, ok := whttp.ErrorBodyAs[ApiError](err); ok {
+//		fmt.Println(apiErr.ErrCode, apiErr.ErrMsg)
+//	}
+func ErrorBodyAs[E any](err error) (*E, bool) {
+	var httpErr *HTTPError
+	if !errors.As(err, &httpErr) {
+		return nil, false
+	}
+	body, ok := httpErr.ErrorBody.(*E)
+	return body, ok
+}
+
+// statusError 为非2xx响应生成*HTTPError，响应体为JSON对象时附带在错误信息中，
+// errorType不为nil时使用codec按该类型解析响应体，codec为nil时使用JSON
+func statusError(resp *http.Response, respBytes []byte, errorType reflect.Type, codec Codec) error {
+	httpErr := &HTTPError{
+		StatusCode: resp.StatusCode,
+		Header:     resp.Header,
+		Body:       respBytes,
+	}
+	if resp.Request != nil {
+		httpErr.Method = resp.Request.Method
+		httpErr.URL = resp.Request.URL.String()
+	}
+	var errorResp map[string]any
+	if jsonErr := json.Unmarshal(respBytes, &errorResp); jsonErr == nil {
+		httpErr.ParsedBody = errorResp
+	}
+	if errorType != nil && len(respBytes) > 0 {
+		if codec == nil {
+			codec = JSONCodec
+		}
+		errorBody := reflect.New(errorType)
+		if codec.Unmarshal(respBytes, errorBody.Interface()) == nil {
+			httpErr.ErrorBody = errorBody.Interface()
+		}
+	}
+	return httpErr
+}
+
+// EnvelopeError 信封模式下，响应中code不为0时返回的错误，实现了wlog.CodedError接口，记录日志时会输出错误码
+type EnvelopeError struct {
+	Code    int    // 响应中的code
+	Message string // 响应中的message
+	Status  int    // HTTP状态码
+}
+
+func (e *EnvelopeError) Error() string {
+	return fmt.Sprintf("envelope code not 0, is %d, message: %s", e.Code, e.Message)
+}
+
+func (e *EnvelopeError) ErrorCode() int {
+	return e.Code
+}
+
+func (e *EnvelopeError) HTTPStatus() int {
+	return e.Status
+}
+
+// IsEnvelopeError 判断err是否为下游服务通过信封返回的业务错误，用法与wresp.IsErrorCode一致
+func IsEnvelopeError(err error) bool {
+	var envelopeErr *EnvelopeError
+	return errors.As(err, &envelopeErr)
+}
+
+// envelope wresp标准返回结构
+type envelope struct {
+	Code    *int            `json:"code"`
+	Message string          `json:"message"`
+	Data    json.RawMessage `json:"data"`
+}
+
+// decodeEnvelope 解析2xx响应的信封结构，返回data部分的原始字节，code不为0时返回*EnvelopeError
+func decodeEnvelope(resp *http.Response, respBytes []byte) ([]byte, error) {
+	var env envelope
+	if err := json.Unmarshal(respBytes, &env); err != nil {
+		return nil, fmt.Errorf("decode response envelope: %w", err)
+	}
+	if env.Code == nil {
+		return nil, errors.New("decode response envelope: code field is missing")
+	}
+	if *env.Code != 0 {
+		return nil, &EnvelopeError{Code: *env.Code, Message: env.Message, Status: resp.StatusCode}
+	}
+	// data为null时与空响应体一样，得到T的零值
+	if string(env.Data) == "null" {
+		return nil, nil
+	}
+	return env.Data, nil
+}
+
+// decodeEnvelopeError 从非2xx响应中解析信封里的业务错误，响应体不是信封结构时返回nil
+func decodeEnvelopeError(resp *http.Response, respBytes []byte) *EnvelopeError {
+	var env envelope
+	if json.Unmarshal(respBytes, &env) != nil || env.Code == nil || *env.Code == 0 {
+		return nil
+	}
+	return &EnvelopeError{Code: *env.Code, Message: env.Message, Status: resp.StatusCode}
+}
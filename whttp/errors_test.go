@@ -0,0 +1,46 @@
+package whttp
+
+import (
+	"net/http"
+	"testing"
+)
+
+type apiError struct {
+	Code    int    `json:"code" xml:"code"`
+	Message string `json:"message" xml:"message"`
+}
+
+func TestErrorTypeUsesResponseCodec(t *testing.T) {
+	srv := newJSONServer(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/xml":
+			w.Header().Set("Content-Type", "application/xml; charset=utf-8")
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`<apiError><code>1001</code><message>bad xml</message></apiError>`))
+		case "/json":
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"code":1002,"message":"bad json"}`))
+		}
+	})
+	cases := []struct {
+		path string
+		want apiError
+	}{
+		{"/xml", apiError{Code: 1001, Message: "bad xml"}},
+		{"/json", apiError{Code: 1002, Message: "bad json"}},
+	}
+	for _, c := range cases {
+		_, err := NewGet[map[string]any]().WithBaseURL(srv.URL + c.path).WithErrorType(&apiError{}).Send()
+		body, ok := ErrorBodyAs[apiError](err)
+		if !ok || *body != c.want {
+			t.Fatalf("%s: got %+v (%v), want %+v", c.path, body, err, c.want)
+		}
+	}
+
+	// WithResponseCodec指定的编解码器同样用于错误响应体
+	_, err := NewGet[map[string]any]().WithBaseURL(srv.URL + "/xml").WithResponseCodec(XMLCodec).WithErrorType(&apiError{}).Send()
+	if body, ok := ErrorBodyAs[apiError](err); !ok || body.Code != 1001 {
+		t.Fatalf("response codec: got %+v (%v)", body, err)
+	}
+}
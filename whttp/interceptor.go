@@ -0,0 +1,51 @@
+package whttp
+
+import (
+	"net/http"
+)
+
+// RoundTrip 执行一次HTTP请求并返回响应，每次重试都会单独调用一次
+type RoundTrip func(req *http.Request) (*http.Response, error)
+
+// Interceptor 请求拦截器，包装下一个RoundTrip，可以在请求发出前修改*http.Request（如添加认证头、签名），
+// 也可以在收到响应后查看*http.Response（如记录日志、统计指标）
+//
+//	func AuthInterceptor(next whttp.RoundTrip) whttp.RoundTrip {
+//		return func(req *http.Request) (*http.Response, error) {
+//			req.Header.Set("Authorization", "Bearer xxx")
+//			return next(req)
+//		}
+//	}
+type Interceptor func(next RoundTrip) RoundTrip
+
+// Use 为Client添加拦截器，作用于之后由该Client派生的所有请求，应在初始化阶段调用
+// Client的拦截器位于外层，先于请求级别通过HttpClient.Use添加的拦截器执行
+func (c *Client) Use(interceptors ...Interceptor) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.interceptors = append(c.interceptors, interceptors...)
+}
+
+func (c *Client) snapshotInterceptors() []Interceptor {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	interceptors := make([]Interceptor, len(c.interceptors))
+	copy(interceptors, c.interceptors)
+	return interceptors
+}
+
+// chainInterceptors 按添加顺序由外到内包装拦截器，最内层为实际发送请求的RoundTrip
+func chainInterceptors(roundTrip RoundTrip, interceptors []Interceptor) RoundTrip {
+	for i := len(interceptors) - 1; i >= 0; i-- {
+		roundTrip = interceptors[i](roundTrip)
+	}
+	return roundTrip
+}
+
+// closeRequestBody 拦截器不调用next直接返回错误时关闭请求体，否则multipart、压缩等通过io.Pipe生成的请求体
+// 对应的Goroutine会一直阻塞；http.Client在发送失败时也会关闭请求体，重复关闭没有影响
+func closeRequestBody(req *http.Request) {
+	if req.Body != nil {
+		_ = req.Body.Close()
+	}
+}
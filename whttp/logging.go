@@ -0,0 +1,171 @@
+package whttp
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mundo-wang/wtool/wlog"
+)
+
+type attemptKeyType struct{}
+
+var attemptKey = attemptKeyType{}
+
+// AttemptFromContext 在拦截器中通过req.Context()获取当前是第几次请求，从1开始，不在whttp请求中时返回0
+func AttemptFromContext(ctx context.Context) int {
+	number, _ := ctx.Value(attemptKey).(int)
+	return number
+}
+
+// LogConfig 请求日志拦截器的配置
+type LogConfig struct {
+	MaskQueryKeys []string // 需要打码的查询参数，如token、sign，不区分大小写
+	RedactHeaders []string // 需要打码的请求头和响应头，默认为Authorization、Proxy-Authorization、Cookie、Set-Cookie
+	MaxBodySize   int      // 记录的请求体和响应体的最大字节数，默认1024，负数表示不记录
+}
+
+var defaultRedactHeaders = []string{"Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie"}
+
+// LoggingInterceptor 返回通过wlog记录每次请求的拦截器，日志中包含请求方法、URL、状态码、耗时、第几次请求、
+// 请求头、响应头以及截断后的请求体和响应体，并携带请求上下文中的traceId
+// 请求出错或状态码为5xx时记录ERROR日志，4xx时记录WARN日志，其余记录INFO日志
+func LoggingInterceptor(cfg LogConfig) Interceptor {
+	if cfg.MaxBodySize == 0 {
+		cfg.MaxBodySize = 1024
+	}
+	if cfg.RedactHeaders == nil {
+		cfg.RedactHeaders = defaultRedactHeaders
+	}
+	return func(next RoundTrip) RoundTrip {
+		return func(req *http.Request) (*http.Response, error) {
+			entry := wlog.Msg("whttp request").Ctx(req.Context()).
+				Field("method", req.Method).
+				Field("url", maskURL(req, cfg.MaskQueryKeys)).
+				Field("attempt", AttemptFromContext(req.Context())).
+				Field("req_headers", redactHeaders(req.Header, cfg.RedactHeaders))
+			if cfg.MaxBodySize > 0 {
+				entry = entry.Field("req_body", requestBodySnippet(req, cfg.MaxBodySize))
+			}
+			start := time.Now()
+			resp, err := next(req)
+			entry = entry.Field("latency_ms", time.Since(start).Milliseconds())
+			if err != nil {
+				entry.Err(err).LevelError()
+				return resp, err
+			}
+			entry = entry.Field("status", resp.StatusCode).
+				Field("resp_headers", redactHeaders(resp.Header, cfg.RedactHeaders))
+			if cfg.MaxBodySize > 0 {
+				entry = entry.Field("resp_body", responseBodySnippet(resp, cfg.MaxBodySize))
+			}
+			switch {
+			case resp.StatusCode >= 500:
+				entry.LevelError()
+			case resp.StatusCode >= 400:
+				entry.LevelWarn()
+			default:
+				entry.LevelInfo()
+			}
+			return resp, nil
+		}
+	}
+}
+
+func maskURL(req *http.Request, maskKeys []string) string {
+	if len(maskKeys) == 0 || req.URL.RawQuery == "" {
+		return req.URL.String()
+	}
+	u := *req.URL
+	query := u.Query()
+	keys := make([]string, 0, len(query))
+	for key := range query {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	// 不使用url.Values.Encode，避免打码后的***被转义为%2A%2A%2A
+	var builder strings.Builder
+	for _, key := range keys {
+		masked := false
+		for _, maskKey := range maskKeys {
+			if strings.EqualFold(key, maskKey) {
+				masked = true
+				break
+			}
+		}
+		for _, value := range query[key] {
+			if builder.Len() > 0 {
+				builder.WriteByte('&')
+			}
+			builder.WriteString(url.QueryEscape(key))
+			builder.WriteByte('=')
+			if masked {
+				builder.WriteString("***")
+			} else {
+				builder.WriteString(url.QueryEscape(value))
+			}
+		}
+	}
+	u.RawQuery = builder.String()
+	return u.String()
+}
+
+func redactHeaders(header http.Header, redact []string) map[string]string {
+	headers := make(map[string]string, len(header))
+	for key, values := range header {
+		headers[key] = strings.Join(values, ", ")
+	}
+	for _, key := range redact {
+		key = http.CanonicalHeaderKey(key)
+		if _, ok := headers[key]; ok {
+			headers[key] = "***"
+		}
+	}
+	return headers
+}
+
+// requestBodySnippet 通过GetBody读取请求体的开头部分，不影响实际发送的请求体
+// multipart请求体可能来自只能读取一次的文件，压缩后的请求体不可读，这里都不读取
+func requestBodySnippet(req *http.Request, maxSize int) string {
+	if req.GetBody == nil {
+		return ""
+	}
+	if strings.HasPrefix(req.Header.Get("Content-Type"), "multipart/") {
+		return "<multipart>"
+	}
+	if encoding := req.Header.Get("Content-Encoding"); encoding != "" {
+		return "<" + encoding + ">"
+	}
+	body, err := req.GetBody()
+	if err != nil {
+		return ""
+	}
+	defer body.Close()
+	return readSnippet(body, maxSize)
+}
+
+// responseBodySnippet 读取响应体的开头部分后重新拼接回响应体，调用方仍能读到完整内容
+// SSE等流式响应读取时会阻塞，这里不读取
+func responseBodySnippet(resp *http.Response, maxSize int) string {
+	if strings.HasPrefix(resp.Header.Get("Content-Type"), "text/event-stream") {
+		return "<stream>"
+	}
+	snippet := make([]byte, maxSize)
+	n, _ := io.ReadFull(resp.Body, snippet)
+	snippet = snippet[:n]
+	resp.Body = struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(snippet), resp.Body), resp.Body}
+	return string(snippet)
+}
+
+func readSnippet(r io.Reader, maxSize int) string {
+	snippet, _ := io.ReadAll(io.LimitReader(r, int64(maxSize)))
+	return string(snippet)
+}
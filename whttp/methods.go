@@ -1,13 +1,18 @@
 package whttp
 
 import (
-	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
+	"hash"
 	"io"
 	"math/rand"
+	"mime/multipart"
 	"net/http"
+	"net/http/httptrace"
 	"net/url"
+	"path/filepath"
+	"reflect"
 	"regexp"
 	"strings"
 	"time"
@@ -15,16 +20,24 @@ import (
 	"github.com/google/go-querystring/query"
 )
 
-// WithBaseURL 设置请求的基础URL地址
+// WithContext 设置请求使用的上下文，上下文取消或超时后，正在进行的请求和重试等待会立即中断
+func (cli *httpClient[T]) WithContext(ctx context.Context) HttpClient[T] {
+	if ctx != nil {
+		cli.ctx = ctx
+	}
+	return cli
+}
+
+// WithBaseURL 设置请求的基础URL地址，传入不带协议的相对路径时拼接在Client配置的BaseURL之后
 func (cli *httpClient[T]) WithBaseURL(baseURL string) HttpClient[T] {
-	cli.baseURL = baseURL
+	cli.baseURL = joinBaseURL(cli.client.cfg.BaseURL, baseURL)
 	return cli
 }
 
-// WithTimeout 设置请求超时时间，timeout为0表示不限制超时
+// WithTimeout 设置单次请求的超时时间（每次重试单独计时），timeout为0表示不限制超时
 func (cli *httpClient[T]) WithTimeout(timeout time.Duration) HttpClient[T] {
 	if timeout > 0 {
-		cli.client.Timeout = timeout
+		cli.timeout = timeout
 	}
 	return cli
 }
@@ -46,6 +59,20 @@ func (cli *httpClient[T]) WithRetry(retryCount int, retryDelay, maxRetryDelay ti
 	return cli
 }
 
+// WithRetryPolicy 设置当前请求的重试策略，覆盖Client配置的重试策略，需配合WithRetry设置重试次数
+func (cli *httpClient[T]) WithRetryPolicy(policy RetryPolicy) HttpClient[T] {
+	if policy != nil {
+		cli.retryPolicy = policy
+	}
+	return cli
+}
+
+// WithAttemptHook 设置每次请求（包括重试）结束后调用的钩子函数，可用于记录重试情况
+func (cli *httpClient[T]) WithAttemptHook(hook func(attempt *Attempt)) HttpClient[T] {
+	cli.attemptHook = hook
+	return cli
+}
+
 // WithJsonBody 将传入的对象序列化为JSON并设置为请求体，同时自动添加Content-Type请求头
 func (cli *httpClient[T]) WithJsonBody(body interface{}) HttpClient[T] {
 	jsonBody, err := json.Marshal(body)
@@ -53,11 +80,86 @@ func (cli *httpClient[T]) WithJsonBody(body interface{}) HttpClient[T] {
 		cli.err = err
 		return cli
 	}
-	cli.jsonBody = jsonBody
+	cli.body = bytesBody(jsonBody)
+	cli.formParts = nil
 	cli.WithHeader("Content-Type", "application/json")
 	return cli
 }
 
+// WithBody 使用指定的编解码器序列化对象并设置为请求体，同时根据编解码器设置Content-Type请求头
+func (cli *httpClient[T]) WithBody(codec Codec, body interface{}) HttpClient[T] {
+	data, err := codec.Marshal(body)
+	if err != nil {
+		cli.err = err
+		return cli
+	}
+	cli.body = bytesBody(data)
+	cli.formParts = nil
+	cli.WithHeader("Content-Type", codec.ContentType())
+	return cli
+}
+
+// WithCompressedBody 使用gzip（EncodingGzip）或deflate（EncodingDeflate）压缩请求体，并设置Content-Encoding请求头
+// 压缩在发送时边读边进行，与设置请求体的方法调用顺序无关
+func (cli *httpClient[T]) WithCompressedBody(encoding string) HttpClient[T] {
+	if encoding != EncodingGzip && encoding != EncodingDeflate {
+		cli.err = fmt.Errorf("unsupported request body encoding: %s", encoding)
+		return cli
+	}
+	cli.compression = encoding
+	cli.WithHeader("Content-Encoding", encoding)
+	return cli
+}
+
+// WithResponseCodec 指定解码响应体使用的编解码器，不再根据响应的Content-Type选择
+func (cli *httpClient[T]) WithResponseCodec(codec Codec) HttpClient[T] {
+	cli.respCodec = codec
+	return cli
+}
+
+// WithFormBody 将url.Values、map[string]string或带url标签的结构体编码为application/x-www-form-urlencoded请求体
+func (cli *httpClient[T]) WithFormBody(body interface{}) HttpClient[T] {
+	values, err := formValues(body)
+	if err != nil {
+		cli.err = err
+		return cli
+	}
+	cli.body = bytesBody([]byte(values.Encode()))
+	cli.formParts = nil
+	cli.WithHeader("Content-Type", "application/x-www-form-urlencoded")
+	return cli
+}
+
+// WithFormField 添加multipart/form-data请求体中的普通字段
+func (cli *httpClient[T]) WithFormField(key, value string) HttpClient[T] {
+	cli.addFormPart(formPart{field: key, value: value})
+	return cli
+}
+
+// WithFormFile 添加multipart/form-data请求体中的文件，文件内容在发送时流式读取
+// reader实现了io.Seeker（如*os.File）时支持重试，否则只能发送一次，失败时直接返回该次的响应或错误，需要重试时请使用WithFormFilePath
+func (cli *httpClient[T]) WithFormFile(field, fileName string, reader io.Reader) HttpClient[T] {
+	_, seekable := reader.(io.Seeker)
+	cli.addFormPart(formPart{field: field, fileName: fileName, open: readerSource(reader), oneShot: !seekable})
+	return cli
+}
+
+// WithFormFilePath 添加multipart/form-data请求体中的文件，每次发送（包括重试）时重新打开该路径的文件
+func (cli *httpClient[T]) WithFormFilePath(field, filePath string) HttpClient[T] {
+	cli.addFormPart(formPart{field: field, fileName: filepath.Base(filePath), open: fileSource(filePath)})
+	return cli
+}
+
+// addFormPart 追加multipart请求体的一个部分，同一个请求的分隔符保持不变，保证重试时Content-Type一致
+func (cli *httpClient[T]) addFormPart(part formPart) {
+	if cli.formParts == nil {
+		cli.boundary = multipart.NewWriter(io.Discard).Boundary()
+	}
+	cli.formParts = append(cli.formParts, part)
+	cli.body = multipartBody(cli.formParts, cli.boundary)
+	cli.WithHeader("Content-Type", "multipart/form-data; boundary="+cli.boundary)
+}
+
 // 用于匹配baseURL模板中的占位符，如/user/{uid}/order/{oid}
 var rePathVar = regexp.MustCompile(`\{([^{}]+)}`)
 
@@ -110,6 +212,50 @@ func (cli *httpClient[T]) WithQueryParamByStruct(params interface{}) HttpClient[
 	return cli
 }
 
+// WithProgress 设置SendTo和Download的进度回调，written为已写入的字节数，total为响应体总大小，未知时为-1
+func (cli *httpClient[T]) WithProgress(progress func(written, total int64)) HttpClient[T] {
+	cli.progress = progress
+	return cli
+}
+
+// WithMaxSize 设置SendTo和Download允许的最大响应体字节数，超过时中止并返回错误，0表示不限制
+func (cli *httpClient[T]) WithMaxSize(maxSize int64) HttpClient[T] {
+	if maxSize > 0 {
+		cli.maxSize = maxSize
+	}
+	return cli
+}
+
+// WithChecksum 设置SendTo和Download的校验和，如WithChecksum(sha256.New(), "9f86d0...")，
+// 写入完成后计算的十六进制摘要与expected不一致时返回错误，Download不会保留校验失败的文件
+func (cli *httpClient[T]) WithChecksum(h hash.Hash, expected string) HttpClient[T] {
+	cli.checksum = h
+	cli.expectedSum = strings.ToLower(expected)
+	return cli
+}
+
+// WithEnvelope 开启信封模式，响应体按wresp的{code, message, data}结构解析，data反序列化为T类型，
+// code不为0时返回*EnvelopeError，可以通过IsEnvelopeError判断或errors.As取出错误码和错误信息
+func (cli *httpClient[T]) WithEnvelope() HttpClient[T] {
+	cli.envelope = true
+	return cli
+}
+
+// WithErrorType 声明非2xx响应体对应的错误类型，如WithErrorType(&ApiError{})，
+// 响应失败时响应体会反序列化为该类型，可以通过ErrorBodyAs取出，该类型实现了error接口时也可以直接使用errors.As
+func (cli *httpClient[T]) WithErrorType(errorType interface{}) HttpClient[T] {
+	t := reflect.TypeOf(errorType)
+	if t == nil {
+		cli.errorType = nil
+		return cli
+	}
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	cli.errorType = t
+	return cli
+}
+
 // WithHeader 添加单个请求头，value为空字符串时忽略
 func (cli *httpClient[T]) WithHeader(key, value string) HttpClient[T] {
 	if value != "" {
@@ -126,55 +272,147 @@ func (cli *httpClient[T]) WithHeaderByMap(headers map[string]string) HttpClient[
 	return cli
 }
 
+// WithAuth 设置当前请求的认证信息提供者，覆盖Client配置的Auth，传入nil表示当前请求不需要认证
+func (cli *httpClient[T]) WithAuth(provider AuthProvider) HttpClient[T] {
+	cli.auth = provider
+	return cli
+}
+
+// WithSigner 设置当前请求的签名器，覆盖Client配置的Signer，传入nil表示当前请求不签名
+func (cli *httpClient[T]) WithSigner(signer Signer) HttpClient[T] {
+	cli.signer = signer
+	return cli
+}
+
+// Use 为当前请求添加拦截器，位于Client拦截器的内层，每次重试都会经过拦截器
+func (cli *httpClient[T]) Use(interceptors ...Interceptor) HttpClient[T] {
+	cli.interceptors = append(cli.interceptors, interceptors...)
+	return cli
+}
+
 // Send 发送HTTP请求并将响应体反序列化为泛型类型T，失败时返回错误
 func (cli *httpClient[T]) Send() (ResponseWrapper[T], error) {
 	if cli.err != nil {
 		return nil, cli.err
 	}
-	httpResp, err := cli.executeRequest()
+	httpResp, stats, err := cli.executeRequest()
 	if err != nil {
 		return nil, err
 	}
 	defer httpResp.Body.Close()
-	return cli.handleResponse(httpResp)
+	return cli.handleResponse(httpResp, stats)
 }
 
-// executeRequest 执行HTTP请求，支持带指数退避和随机抖动的超时重试机制
-func (cli *httpClient[T]) executeRequest() (*http.Response, error) {
-	var lastErr error
+// SendTo 发送HTTP请求并将2xx响应体流式写入w，不会把响应体整体加载到内存，适用于下载大文件
+// 返回的ResponseWrapper可以获取响应头和请求次数，GetRespBytes和GetRespData为空值
+func (cli *httpClient[T]) SendTo(w io.Writer) (ResponseWrapper[T], error) {
+	if cli.err != nil {
+		return nil, cli.err
+	}
+	httpResp, stats, err := cli.executeRequest()
+	if err != nil {
+		return nil, err
+	}
+	defer httpResp.Body.Close()
+	return cli.handleStreamResponse(httpResp, stats, w)
+}
+
+// Download 发送HTTP请求并将响应体保存到filePath，先写入同目录下的临时文件，完整写入并校验通过后再重命名，
+// 保证filePath要么是完整的文件，要么保持原样
+func (cli *httpClient[T]) Download(filePath string) (ResponseWrapper[T], error) {
+	if cli.err != nil {
+		return nil, cli.err
+	}
+	return downloadFile(filePath, cli.SendTo)
+}
+
+// executeRequest 执行HTTP请求，按重试策略进行带指数退避和随机抖动的重试，返回最终响应和包括请求次数、耗时在内的统计信息
+func (cli *httpClient[T]) executeRequest() (*http.Response, *requestStats, error) {
 	attempts := 1 + cli.retryCount // 1次正常请求 + N次重试
-	for attempt := 0; attempt < attempts; attempt++ {
-		if attempt > 0 {
-			// 指数退避：delay = retryDelay * 2^(attempt-1)
-			delay := cli.retryDelay << (attempt - 1)
-			if delay > cli.maxRetryDelay {
-				delay = cli.maxRetryDelay
+	roundTrip := chainInterceptors(cli.client.authRoundTrip(cli.auth, cli.signer), cli.interceptors)
+	if cli.direct {
+		roundTrip = cli.client.do
+	}
+	stats := &requestStats{start: time.Now()}
+	var delay time.Duration
+	for number := 1; ; number++ {
+		if number > 1 {
+			if err := sleepWithContext(cli.ctx, delay); err != nil {
+				return nil, stats, err
 			}
-			// 随机抖动：0.5 ~ 1.5 倍
-			jitterFactor := 0.5 + rand.Float64() // rand.Float64() ∈ [0,1)
-			jitterDelay := time.Duration(float64(delay) * jitterFactor)
-			time.Sleep(jitterDelay)
 		}
 		// 每次创建新的Request，因为调用Do方法会导致Body内部数据被消耗
-		req, err := cli.buildRequest()
+		attemptCtx, cancel := cli.attemptContext()
+		attemptCtx = context.WithValue(attemptCtx, attemptKey, number)
+		stats.attempts, stats.trace = number, newTimingTrace()
+		attemptCtx = httptrace.WithClientTrace(attemptCtx, stats.trace.clientTrace())
+		req, err := cli.buildRequest(attemptCtx)
 		if err != nil {
-			return nil, err
+			cancel()
+			stats.attempts = number - 1
+			return nil, stats, err
 		}
-		resp, err := cli.client.Do(req)
-		if err == nil {
-			return resp, nil
+		resp, err := roundTrip(req)
+		attempt := &Attempt{Number: number, Request: req, Response: resp, Err: err}
+		if cli.attemptHook != nil {
+			cli.attemptHook(attempt)
 		}
-		// 只对超时错误进行重试处理，非超时错误直接返回
-		if !isTimeoutError(err) {
-			return nil, err
+		// 上下文已经结束、重试次数用完或请求体无法再次读取时不再重试，直接返回本次的真实结果
+		retry, wait := false, time.Duration(0)
+		if number < attempts && cli.ctx.Err() == nil && replayable(cli.formParts) {
+			retry, wait = cli.retryPolicy.ShouldRetry(attempt)
+		}
+		if err != nil {
+			// 拦截器可能没有调用下一层就返回了错误，统一关闭请求体
+			closeRequestBody(req)
+		}
+		if !retry {
+			if err != nil {
+				cancel()
+				return nil, stats, err
+			}
+			// 超时上下文需要覆盖读取响应体的过程，因此在响应体关闭时才释放
+			resp.Body = &cancelOnClose{ReadCloser: resp.Body, cancel: cancel}
+			return resp, stats, nil
 		}
-		lastErr = err
+		if resp != nil {
+			// 读完少量剩余数据再关闭，使底层连接可以放回连接池复用
+			_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+			_ = resp.Body.Close()
+		}
+		cancel()
+		delay = cli.retryBackoff(number, wait)
+	}
+}
+
+// retryBackoff 计算第number次请求失败后的等待时长，重试策略指定了等待时长时优先使用，但不超过最大重试延迟
+func (cli *httpClient[T]) retryBackoff(number int, wait time.Duration) time.Duration {
+	if wait > 0 {
+		if wait > cli.maxRetryDelay {
+			wait = cli.maxRetryDelay
+		}
+		return wait
+	}
+	// 指数退避：delay = retryDelay * 2^(number-1)
+	delay := cli.retryDelay << (number - 1)
+	if delay <= 0 || delay > cli.maxRetryDelay {
+		delay = cli.maxRetryDelay
 	}
-	return nil, lastErr
+	// 随机抖动：0.5 ~ 1.5 倍
+	jitterFactor := 0.5 + rand.Float64() // rand.Float64() ∈ [0,1)
+	return time.Duration(float64(delay) * jitterFactor)
+}
+
+// attemptContext 为单次请求创建上下文，设置了超时时间时，超时从本次请求开始计算
+func (cli *httpClient[T]) attemptContext() (context.Context, context.CancelFunc) {
+	if cli.timeout > 0 {
+		return context.WithTimeout(cli.ctx, cli.timeout)
+	}
+	return context.WithCancel(cli.ctx)
 }
 
 // buildRequest 根据已配置的URL、查询参数、请求体和请求头构建http.Request对象
-func (cli *httpClient[T]) buildRequest() (*http.Request, error) {
+func (cli *httpClient[T]) buildRequest(ctx context.Context) (*http.Request, error) {
 	var fullURL string
 	if len(cli.queryParams) > 0 {
 		fullURL = fmt.Sprintf("%s?%s", cli.baseURL, cli.queryParams.Encode())
@@ -182,14 +420,38 @@ func (cli *httpClient[T]) buildRequest() (*http.Request, error) {
 		fullURL = cli.baseURL
 	}
 	cli.fullURL = fullURL
+	bodyFn := cli.body
+	if bodyFn != nil && cli.compression != "" {
+		bodyFn = compressBody(bodyFn, cli.compression)
+	}
 	var body io.Reader
-	if cli.jsonBody != nil {
-		body = bytes.NewBuffer(cli.jsonBody)
+	if bodyFn != nil {
+		var err error
+		if body, err = bodyFn(); err != nil {
+			return nil, err
+		}
 	}
-	req, err := http.NewRequest(cli.method, fullURL, body)
+	req, err := http.NewRequestWithContext(ctx, cli.method, fullURL, body)
 	if err != nil {
+		// 请求体可能已经打开了文件或启动了写入Goroutine，需要关闭
+		if closer, ok := body.(io.Closer); ok {
+			_ = closer.Close()
+		}
 		return nil, err
 	}
+	if bodyFn != nil {
+		// 遇到307、308重定向时，http.Client通过GetBody重新获取请求体
+		req.GetBody = func() (io.ReadCloser, error) {
+			body, err := bodyFn()
+			if err != nil {
+				return nil, err
+			}
+			if rc, ok := body.(io.ReadCloser); ok {
+				return rc, nil
+			}
+			return io.NopCloser(body), nil
+		}
+	}
 	for key, value := range cli.headers {
 		req.Header.Set(key, value)
 	}
@@ -197,39 +459,97 @@ func (cli *httpClient[T]) buildRequest() (*http.Request, error) {
 }
 
 // handleResponse 读取HTTP响应体，2xx状态码时反序列化为T类型，否则返回包含状态码的错误
-func (cli *httpClient[T]) handleResponse(resp *http.Response) (ResponseWrapper[T], error) {
+// 开启了信封模式时，先解析wresp的{code, message, data}结构，再将data反序列化为T类型
+func (cli *httpClient[T]) handleResponse(resp *http.Response, stats *requestStats) (ResponseWrapper[T], error) {
 	respBytes, err := io.ReadAll(resp.Body)
 	if err != nil {
 		return nil, err
 	}
-	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
-		var respData T
-		switch any(respData).(type) {
-		// 如果T为[]byte或json.RawMessage，说明不需要反序列化JSON到具体类型，直接赋值字节数组
-		case []byte:
-			respData = any(respBytes).(T)
-		case json.RawMessage:
-			respData = any(json.RawMessage(respBytes)).(T)
-		default:
-			if len(respBytes) > 0 {
-				if err = json.Unmarshal(respBytes, &respData); err != nil {
-					return nil, err
-				}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		err = statusError(resp, respBytes, cli.errorType, cli.errorCodec(resp))
+		// wresp返回业务错误时HTTP状态码一般不是2xx，此时从信封中取出错误码和错误信息
+		// 同时通过WithErrorType声明了错误类型时，以声明的错误类型为准
+		httpErr := err.(*HTTPError)
+		if cli.envelope && httpErr.ErrorBody == nil {
+			if envelopeErr := decodeEnvelopeError(resp, respBytes); envelopeErr != nil {
+				httpErr.ErrorBody = envelopeErr
 			}
 		}
-		handler := &responseWrapper[T]{
-			respHeaders: resp.Header,
-			respBytes:   respBytes,
-			respData:    respData,
+		return nil, err
+	}
+	dataBytes := respBytes
+	if cli.envelope {
+		if dataBytes, err = decodeEnvelope(resp, respBytes); err != nil {
+			return nil, err
+		}
+	}
+	respData, err := decodeData[T](dataBytes, cli.responseCodec(resp))
+	if err != nil {
+		return nil, err
+	}
+	handler := newResponseWrapper[T](resp, stats)
+	handler.respBytes = respBytes
+	handler.respData = respData
+	return handler, nil
+}
+
+// responseCodec 选择解码响应体的编解码器：优先使用WithResponseCodec指定的，信封模式下data字段固定为JSON，
+// 否则根据响应的Content-Type从已注册的编解码器中查找，找不到时使用JSON
+func (cli *httpClient[T]) responseCodec(resp *http.Response) Codec {
+	if cli.respCodec != nil {
+		return cli.respCodec
+	}
+	if !cli.envelope {
+		if codec, ok := CodecFor(resp.Header.Get("Content-Type")); ok {
+			return codec
+		}
+	}
+	return JSONCodec
+}
+
+// errorCodec 选择解析错误响应体的编解码器，与responseCodec相同但不受信封模式影响，错误响应体按其自身的Content-Type解析
+func (cli *httpClient[T]) errorCodec(resp *http.Response) Codec {
+	if cli.respCodec != nil {
+		return cli.respCodec
+	}
+	if codec, ok := CodecFor(resp.Header.Get("Content-Type")); ok {
+		return codec
+	}
+	return JSONCodec
+}
+
+// decodeData 使用codec将字节数组反序列化为T类型，内容为空时返回T的零值
+func decodeData[T any](data []byte, codec Codec) (T, error) {
+	var respData T
+	switch any(respData).(type) {
+	// 如果T为[]byte或json.RawMessage，说明不需要反序列化JSON到具体类型，直接赋值字节数组
+	case []byte:
+		respData = any(data).(T)
+	case json.RawMessage:
+		respData = any(json.RawMessage(data)).(T)
+	default:
+		if len(data) > 0 {
+			if err := codec.Unmarshal(data, &respData); err != nil {
+				return respData, err
+			}
 		}
-		return handler, nil
 	}
-	err = fmt.Errorf("http status code not 2xx, is %d", resp.StatusCode)
-	var errorResp map[string]any
-	if jsonErr := json.Unmarshal(respBytes, &errorResp); jsonErr == nil {
-		err = fmt.Errorf("%w, body: %v", err, errorResp)
+	return respData, nil
+}
+
+// newResponseWrapper 根据最终响应和统计信息创建responseWrapper，响应体读取完成后调用，总耗时包含读取响应体的时间
+func newResponseWrapper[T any](resp *http.Response, stats *requestStats) *responseWrapper[T] {
+	handler := &responseWrapper[T]{
+		respHeaders: resp.Header,
+		statusCode:  resp.StatusCode,
+		attempts:    stats.attempts,
+		latency:     time.Since(stats.start),
+		timing:      stats.trace.result(),
+	}
+	if resp.Request != nil {
+		handler.finalURL = resp.Request.URL.String()
 	}
-	return nil, err
+	return handler
 }
 
 // GetRespBytes 返回响应体的原始字节数组
@@ -242,6 +562,31 @@ func (cli *responseWrapper[T]) GetRespData() T {
 	return cli.respData
 }
 
+// GetAttempts 返回得到该响应共发出的请求次数，1表示没有发生重试
+func (cli *responseWrapper[T]) GetAttempts() int {
+	return cli.attempts
+}
+
+// GetStatusCode 返回响应的HTTP状态码
+func (cli *responseWrapper[T]) GetStatusCode() int {
+	return cli.statusCode
+}
+
+// GetFinalURL 返回最终响应对应的URL，发生重定向时为重定向后的地址
+func (cli *responseWrapper[T]) GetFinalURL() string {
+	return cli.finalURL
+}
+
+// GetLatency 返回从发送第一次请求到读取完响应体的总耗时，包括所有重试和重试等待时间
+func (cli *responseWrapper[T]) GetLatency() time.Duration {
+	return cli.latency
+}
+
+// GetTiming 返回最后一次请求的DNS解析、建立连接、TLS握手和首字节耗时
+func (cli *responseWrapper[T]) GetTiming() Timing {
+	return cli.timing
+}
+
 // GetRespHeader 获取响应头中指定key的第一个值，不存在则返回空字符串
 func (cli *responseWrapper[T]) GetRespHeader(key string) string {
 	value := cli.respHeaders.Get(key)
@@ -252,4 +597,4 @@ func (cli *responseWrapper[T]) GetRespHeader(key string) string {
 func (cli *responseWrapper[T]) GetRespHeaderMulti(key string) []string {
 	values := cli.respHeaders.Values(key)
 	return values
-}
\ No newline at end of file
+}
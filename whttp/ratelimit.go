@@ -0,0 +1,266 @@
+package whttp
+
+import (
+	"context"
+	"io"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// RateLimit 一组限流参数，各字段为0表示对应维度不限制
+type RateLimit struct {
+	QPS         float64 // 每秒允许发出的请求数
+	Burst       int     // 令牌桶容量，即允许的突发请求数，默认为QPS向上取整
+	MaxInFlight int     // 最大并发请求数，请求从发出到响应体关闭期间占用一个名额
+}
+
+// RateLimitConfig 客户端限流配置，一个请求需要同时满足Client级别和目标主机级别的限制
+type RateLimitConfig struct {
+	Client  RateLimit            // 由该限流器保护的所有请求共享的限制
+	PerHost RateLimit            // 每个目标主机（host:port）各自的默认限制
+	Hosts   map[string]RateLimit // 指定目标主机的限制，覆盖PerHost
+	// 是否根据服务端的响应调整发送到该主机的速度：收到Retry-After时暂停到指定时间，
+	// X-RateLimit-Remaining为0时暂停到X-RateLimit-Reset指定的时间（秒数或Unix时间戳），
+	// 没有Reset时清空令牌桶中积累的令牌（未配置QPS时不生效）；只影响响应所属的主机，不影响Client级别的限制
+	Adaptive bool
+}
+
+// RateLimiter 令牌桶限流与并发数限制，等待期间请求上下文结束时立即返回上下文的错误
+type RateLimiter struct {
+	cfg    RateLimitConfig
+	client *limit
+	mu     sync.Mutex
+	hosts  map[string]*limit
+}
+
+type limit struct {
+	bucket   *tokenBucket  // 为nil表示不限制QPS
+	inFlight chan struct{} // 为nil表示不限制并发数
+
+	mu          sync.Mutex
+	pausedUntil time.Time // 没有令牌桶时，自适应限流暂停发送的截止时间
+}
+
+func NewRateLimiter(cfg RateLimitConfig) *RateLimiter {
+	return &RateLimiter{
+		cfg:    cfg,
+		client: newLimit(cfg.Client),
+		hosts:  make(map[string]*limit),
+	}
+}
+
+func newLimit(rl RateLimit) *limit {
+	l := &limit{}
+	if rl.QPS > 0 {
+		burst := rl.Burst
+		if burst <= 0 {
+			burst = int(math.Ceil(rl.QPS))
+		}
+		l.bucket = &tokenBucket{rate: rl.QPS, burst: float64(burst), tokens: float64(burst), last: time.Now()}
+	}
+	if rl.MaxInFlight > 0 {
+		l.inFlight = make(chan struct{}, rl.MaxInFlight)
+	}
+	return l
+}
+
+func (r *RateLimiter) hostLimit(host string) *limit {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	l, ok := r.hosts[host]
+	if !ok {
+		rl, ok := r.cfg.Hosts[host]
+		if !ok {
+			rl = r.cfg.PerHost
+		}
+		l = newLimit(rl)
+		r.hosts[host] = l
+	}
+	return l
+}
+
+// Interceptor 返回限流拦截器，每次请求（包括重试）都需要获取令牌和并发名额
+func (r *RateLimiter) Interceptor() Interceptor {
+	return func(next RoundTrip) RoundTrip {
+		return func(req *http.Request) (*http.Response, error) {
+			host := r.hostLimit(req.URL.Host)
+			limits := []*limit{r.client, host}
+			release, err := acquireLimits(req.Context(), limits)
+			if err != nil {
+				closeRequestBody(req)
+				return nil, err
+			}
+			resp, err := next(req)
+			if err != nil {
+				release()
+				return resp, err
+			}
+			if r.cfg.Adaptive {
+				host.adapt(resp)
+			}
+			resp.Body = &releaseOnClose{ReadCloser: resp.Body, release: release}
+			return resp, nil
+		}
+	}
+}
+
+// acquireLimits 依次获取并发名额和令牌，任一步失败时归还已获取的名额，成功时返回释放并发名额的函数
+func acquireLimits(ctx context.Context, limits []*limit) (func(), error) {
+	var acquired []*limit
+	release := func() {
+		for _, l := range acquired {
+			<-l.inFlight
+		}
+	}
+	for _, l := range limits {
+		if l.inFlight == nil {
+			continue
+		}
+		select {
+		case l.inFlight <- struct{}{}:
+			acquired = append(acquired, l)
+		case <-ctx.Done():
+			release()
+			return nil, ctx.Err()
+		}
+	}
+	for _, l := range limits {
+		if l.bucket == nil {
+			if err := l.waitPause(ctx); err != nil {
+				release()
+				return nil, err
+			}
+			continue
+		}
+		if err := l.bucket.wait(ctx); err != nil {
+			release()
+			return nil, err
+		}
+	}
+	var once sync.Once
+	return func() { once.Do(release) }, nil
+}
+
+// adapt 根据服务端返回的限流响应头暂停发送
+func (l *limit) adapt(resp *http.Response) {
+	if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusServiceUnavailable {
+		if d := parseRetryAfter(resp.Header.Get("Retry-After")); d > 0 {
+			l.pause(time.Now().Add(d))
+			return
+		}
+	}
+	if resp.Header.Get("X-RateLimit-Remaining") != "0" {
+		return
+	}
+	if reset, err := strconv.ParseInt(resp.Header.Get("X-RateLimit-Reset"), 10, 64); err == nil && reset > 0 {
+		// 数值很大时为Unix时间戳，否则为距离重置的秒数
+		if reset > 1e9 {
+			l.pause(time.Unix(reset, 0))
+		} else {
+			l.pause(time.Now().Add(time.Duration(reset) * time.Second))
+		}
+		return
+	}
+	if l.bucket != nil {
+		l.bucket.drain()
+	}
+}
+
+// pause 暂停发送到until，有令牌桶时由令牌桶处理，保证暂停结束后仍按QPS发送
+func (l *limit) pause(until time.Time) {
+	if l.bucket != nil {
+		l.bucket.pause(until)
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if until.After(l.pausedUntil) {
+		l.pausedUntil = until
+	}
+}
+
+// waitPause 没有令牌桶时等待到暂停结束
+func (l *limit) waitPause(ctx context.Context) error {
+	l.mu.Lock()
+	delay := time.Until(l.pausedUntil)
+	l.mu.Unlock()
+	if delay <= 0 {
+		return nil
+	}
+	return sleepWithContext(ctx, delay)
+}
+
+// tokenBucket 令牌桶，令牌以rate的速度持续补充，最多积累burst个
+type tokenBucket struct {
+	mu          sync.Mutex
+	rate        float64
+	burst       float64
+	tokens      float64
+	last        time.Time
+	pausedUntil time.Time
+}
+
+// wait 预占一个令牌并等待到令牌可用，上下文结束时归还令牌
+func (b *tokenBucket) wait(ctx context.Context) error {
+	b.mu.Lock()
+	now := time.Now()
+	b.refill(now)
+	b.tokens--
+	delay := time.Duration(0)
+	if b.tokens < 0 {
+		delay = time.Duration(-b.tokens / b.rate * float64(time.Second))
+	}
+	if pause := b.pausedUntil.Sub(now); pause > delay {
+		delay = pause
+	}
+	b.mu.Unlock()
+	if delay <= 0 {
+		return nil
+	}
+	if err := sleepWithContext(ctx, delay); err != nil {
+		b.mu.Lock()
+		b.tokens++
+		b.mu.Unlock()
+		return err
+	}
+	return nil
+}
+
+func (b *tokenBucket) refill(now time.Time) {
+	elapsed := now.Sub(b.last).Seconds()
+	b.last = now
+	b.tokens = math.Min(b.burst, b.tokens+elapsed*b.rate)
+}
+
+func (b *tokenBucket) pause(until time.Time) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if until.After(b.pausedUntil) {
+		b.pausedUntil = until
+	}
+}
+
+// drain 清空当前积累的令牌，之后只能按rate的速度发送
+func (b *tokenBucket) drain() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.refill(time.Now())
+	if b.tokens > 0 {
+		b.tokens = 0
+	}
+}
+
+// releaseOnClose 在响应体关闭时归还并发名额
+type releaseOnClose struct {
+	io.ReadCloser
+	release func()
+}
+
+func (r *releaseOnClose) Close() error {
+	err := r.ReadCloser.Close()
+	r.release()
+	return err
+}
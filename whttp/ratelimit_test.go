@@ -0,0 +1,101 @@
+package whttp
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func newJSONServer(t *testing.T, handler http.HandlerFunc) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func okHandler(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write([]byte(`{}`))
+}
+
+func TestRateLimiterQPS(t *testing.T) {
+	srv := newJSONServer(t, okHandler)
+	client := NewClient(ClientConfig{RateLimiter: NewRateLimiter(RateLimitConfig{Client: RateLimit{QPS: 10, Burst: 1}})})
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		if _, err := Get[map[string]any](client).WithBaseURL(srv.URL).Send(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// 第一个请求使用初始令牌，之后每个请求等待100毫秒
+	if elapsed := time.Since(start); elapsed < 180*time.Millisecond {
+		t.Fatalf("3 requests at 10 qps took %v", elapsed)
+	}
+}
+
+func TestRateLimiterMaxInFlight(t *testing.T) {
+	release := make(chan struct{})
+	srv := newJSONServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow" {
+			<-release
+		}
+		okHandler(w, r)
+	})
+	client := NewClient(ClientConfig{RateLimiter: NewRateLimiter(RateLimitConfig{PerHost: RateLimit{MaxInFlight: 1}})})
+	done := make(chan error, 1)
+	go func() {
+		_, err := Get[map[string]any](client).WithBaseURL(srv.URL + "/slow").Send()
+		done <- err
+	}()
+	time.Sleep(50 * time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	_, err := Get[map[string]any](client).WithContext(ctx).WithBaseURL(srv.URL).Send()
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected to wait for the in-flight slot, got %v", err)
+	}
+
+	close(release)
+	if err = <-done; err != nil {
+		t.Fatal(err)
+	}
+	if _, err = Get[map[string]any](client).WithBaseURL(srv.URL).Send(); err != nil {
+		t.Fatalf("slot should be released after the body is closed: %v", err)
+	}
+}
+
+func TestRateLimiterAdaptivePausesOnlyThatHost(t *testing.T) {
+	limited := newJSONServer(t, func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Retry-After", "2")
+		w.WriteHeader(http.StatusTooManyRequests)
+	})
+	other := newJSONServer(t, okHandler)
+	// 只配置并发数，没有令牌桶时暂停同样生效
+	limiter := NewRateLimiter(RateLimitConfig{PerHost: RateLimit{MaxInFlight: 4}, Adaptive: true})
+	client := NewClient(ClientConfig{RateLimiter: limiter})
+
+	_, err := Get[map[string]any](client).WithBaseURL(limited.URL).Send()
+	var httpErr *HTTPError
+	if !errors.As(err, &httpErr) || httpErr.StatusCode != http.StatusTooManyRequests {
+		t.Fatalf("expected 429, got %v", err)
+	}
+
+	start := time.Now()
+	if _, err = Get[map[string]any](client).WithBaseURL(other.URL).Send(); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Fatalf("request to another host waited %v", elapsed)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	_, err = Get[map[string]any](client).WithContext(ctx).WithBaseURL(limited.URL).Send()
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected the limited host to be paused, got %v", err)
+	}
+}
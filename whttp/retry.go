@@ -0,0 +1,92 @@
+package whttp
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"syscall"
+	"time"
+)
+
+// Attempt 单次请求的结果，供重试策略和请求钩子判断使用
+type Attempt struct {
+	Number   int            // 第几次请求，从1开始，大于1表示重试
+	Request  *http.Request  // 本次发出的请求
+	Response *http.Response // 本次收到的响应，请求出错时为nil，钩子中不要读取或关闭响应体
+	Err      error          // 本次请求的错误
+}
+
+// RetryPolicy 重试策略，根据单次请求的结果判断是否需要重试
+// 返回的等待时长大于0时使用该时长（不超过最大重试延迟），否则按指数退避计算等待时长
+type RetryPolicy interface {
+	ShouldRetry(attempt *Attempt) (bool, time.Duration)
+}
+
+// RetryPolicyFunc 把普通函数适配为RetryPolicy
+type RetryPolicyFunc func(attempt *Attempt) (bool, time.Duration)
+
+func (f RetryPolicyFunc) ShouldRetry(attempt *Attempt) (bool, time.Duration) {
+	return f(attempt)
+}
+
+// DefaultRetryPolicy 默认重试策略：
+//  1. 网络超时：所有请求方法都重试
+//  2. 连接被重置、被拒绝或被提前关闭：仅幂等请求重试
+//  3. 429状态码：所有请求方法都重试，服务端未处理该请求
+//  4. 502、503、504状态码：仅幂等请求重试
+//
+// 其中幂等请求指GET、HEAD、OPTIONS、TRACE、PUT、DELETE请求，以及携带了Idempotency-Key请求头的请求
+// 响应中带有Retry-After头时，按其指定的时间等待
+var DefaultRetryPolicy RetryPolicy = RetryPolicyFunc(defaultShouldRetry)
+
+func defaultShouldRetry(attempt *Attempt) (bool, time.Duration) {
+	if attempt.Err != nil {
+		if isTimeoutError(attempt.Err) {
+			return true, 0
+		}
+		return isConnectionError(attempt.Err) && isIdempotent(attempt.Request), 0
+	}
+	switch attempt.Response.StatusCode {
+	case http.StatusTooManyRequests:
+		return true, parseRetryAfter(attempt.Response.Header.Get("Retry-After"))
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		if isIdempotent(attempt.Request) {
+			return true, parseRetryAfter(attempt.Response.Header.Get("Retry-After"))
+		}
+	}
+	return false, 0
+}
+
+func isIdempotent(req *http.Request) bool {
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return req.Header.Get("Idempotency-Key") != ""
+}
+
+// isConnectionError 判断是否为连接层面的错误，此类错误通常可以通过重新建立连接恢复
+func isConnectionError(err error) bool {
+	return errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, syscall.EPIPE) ||
+		errors.Is(err, io.EOF) ||
+		errors.Is(err, io.ErrUnexpectedEOF)
+}
+
+// parseRetryAfter 解析Retry-After响应头，支持秒数和HTTP日期两种格式，无法解析时返回0
+func parseRetryAfter(value string) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		if d := time.Until(date); d > 0 {
+			return d
+		}
+	}
+	return 0
+}
@@ -0,0 +1,288 @@
+package whttp
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Signer 对请求进行签名，位于拦截器、认证、熔断和限流的内层，在请求实际发出前调用，
+// 每次发送（包括重试和收到401后的重新认证）都会重新签名，签名时可以看到Authorization等其他层添加的请求头
+// body为完整的请求体内容，没有请求体时为nil
+type Signer interface {
+	Sign(req *http.Request, body []byte) error
+}
+
+// SignerFunc 函数形式的Signer
+type SignerFunc func(req *http.Request, body []byte) error
+
+func (f SignerFunc) Sign(req *http.Request, body []byte) error {
+	return f(req, body)
+}
+
+// signInterceptor 在请求实际发出前签名，保证时间戳和随机数在每次发送时重新生成
+func signInterceptor(signer Signer) Interceptor {
+	return func(next RoundTrip) RoundTrip {
+		return func(req *http.Request) (*http.Response, error) {
+			if err := signRequest(req, signer); err != nil {
+				closeRequestBody(req)
+				return nil, err
+			}
+			return next(req)
+		}
+	}
+}
+
+// signRequest 读取请求体交给Signer签名，再用读取到的内容替换请求体
+// 签名需要完整的请求体，因此multipart等流式请求体会被整体读入内存
+func signRequest(req *http.Request, signer Signer) error {
+	var body []byte
+	if req.Body != nil && req.Body != http.NoBody {
+		var err error
+		body, err = io.ReadAll(req.Body)
+		_ = req.Body.Close()
+		if err != nil {
+			return fmt.Errorf("read request body for signing: %w", err)
+		}
+		req.Body = io.NopCloser(bytes.NewReader(body))
+		req.ContentLength = int64(len(body))
+		req.GetBody = func() (io.ReadCloser, error) {
+			return io.NopCloser(bytes.NewReader(body)), nil
+		}
+	}
+	return signer.Sign(req, body)
+}
+
+// CanonicalRequest 参与签名的请求要素
+type CanonicalRequest struct {
+	Method     string
+	Path       string // 转义后的请求路径，为空时为/
+	Query      string // 按参数名、参数值排序后重新编码的查询字符串
+	BodyDigest string // 请求体SHA256摘要的十六进制小写形式，没有请求体时为空内容的摘要
+	Timestamp  string
+	Nonce      string
+	Request    *http.Request // 原始请求，自定义规范化方式需要其他请求头时使用
+}
+
+// Canonicalizer 将请求要素拼接为待签名字符串
+type Canonicalizer func(cr *CanonicalRequest) string
+
+// DefaultCanonicalizer 按Method、Path、Query、BodyDigest、Timestamp、Nonce的顺序以换行符连接
+func DefaultCanonicalizer(cr *CanonicalRequest) string {
+	return strings.Join([]string{cr.Method, cr.Path, cr.Query, cr.BodyDigest, cr.Timestamp, cr.Nonce}, "\n")
+}
+
+// HMACSignerConfig 通用HMAC-SHA256签名配置，未设置的字段使用默认值
+type HMACSignerConfig struct {
+	Key             []byte                 // 签名密钥
+	KeyID           string                 // 密钥标识，非空时通过KeyIDHeader发送
+	SignatureHeader string                 // 签名所在请求头，默认X-Signature
+	TimestampHeader string                 // 时间戳所在请求头，默认X-Timestamp
+	NonceHeader     string                 // 随机数所在请求头，默认X-Nonce
+	KeyIDHeader     string                 // 密钥标识所在请求头，默认X-Key-Id
+	Canonicalize    Canonicalizer          // 待签名字符串的拼接方式，默认DefaultCanonicalizer
+	Timestamp       func(time.Time) string // 时间戳格式，默认Unix秒数
+	Base64          bool                   // 为true时签名使用Base64编码，否则使用十六进制小写
+	Now             func() time.Time       // 当前时间，默认time.Now，便于对接本地环境时固定时间
+}
+
+type hmacSigner struct {
+	cfg HMACSignerConfig
+}
+
+// NewHMACSigner 创建通用HMAC-SHA256签名器，适用于支付、开放平台等要求
+// 排序查询参数 + 请求体摘要 + 时间戳 + 随机数签名的接口
+func NewHMACSigner(cfg HMACSignerConfig) Signer {
+	if cfg.SignatureHeader == "" {
+		cfg.SignatureHeader = "X-Signature"
+	}
+	if cfg.TimestampHeader == "" {
+		cfg.TimestampHeader = "X-Timestamp"
+	}
+	if cfg.NonceHeader == "" {
+		cfg.NonceHeader = "X-Nonce"
+	}
+	if cfg.KeyIDHeader == "" {
+		cfg.KeyIDHeader = "X-Key-Id"
+	}
+	if cfg.Canonicalize == nil {
+		cfg.Canonicalize = DefaultCanonicalizer
+	}
+	if cfg.Timestamp == nil {
+		cfg.Timestamp = func(t time.Time) string { return strconv.FormatInt(t.Unix(), 10) }
+	}
+	if cfg.Now == nil {
+		cfg.Now = time.Now
+	}
+	return &hmacSigner{cfg: cfg}
+}
+
+func (s *hmacSigner) Sign(req *http.Request, body []byte) error {
+	nonce, err := newNonce()
+	if err != nil {
+		return err
+	}
+	cr := &CanonicalRequest{
+		Method:     req.Method,
+		Path:       canonicalPath(req.URL),
+		Query:      canonicalQuery(req.URL.Query(), url.QueryEscape),
+		BodyDigest: sha256Hex(body),
+		Timestamp:  s.cfg.Timestamp(s.cfg.Now()),
+		Nonce:      nonce,
+		Request:    req,
+	}
+	mac := hmac.New(sha256.New, s.cfg.Key)
+	mac.Write([]byte(s.cfg.Canonicalize(cr)))
+	sum := mac.Sum(nil)
+	signature := hex.EncodeToString(sum)
+	if s.cfg.Base64 {
+		signature = base64.StdEncoding.EncodeToString(sum)
+	}
+	req.Header.Set(s.cfg.TimestampHeader, cr.Timestamp)
+	req.Header.Set(s.cfg.NonceHeader, cr.Nonce)
+	if s.cfg.KeyID != "" {
+		req.Header.Set(s.cfg.KeyIDHeader, s.cfg.KeyID)
+	}
+	req.Header.Set(s.cfg.SignatureHeader, signature)
+	return nil
+}
+
+// SigV4Config AWS Signature Version 4签名配置，可用于S3、MinIO等兼容服务及其本地替身
+type SigV4Config struct {
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string           // 临时凭证的会话token，非空时通过X-Amz-Security-Token发送
+	Region          string           // 区域，默认us-east-1
+	Service         string           // 服务名，如s3
+	UnsignedPayload bool             // 为true时不计算请求体摘要，x-amz-content-sha256使用UNSIGNED-PAYLOAD
+	Now             func() time.Time // 当前时间，默认time.Now
+}
+
+type sigV4Signer struct {
+	cfg SigV4Config
+}
+
+func NewSigV4Signer(cfg SigV4Config) Signer {
+	if cfg.Region == "" {
+		cfg.Region = "us-east-1"
+	}
+	if cfg.Now == nil {
+		cfg.Now = time.Now
+	}
+	return &sigV4Signer{cfg: cfg}
+}
+
+func (s *sigV4Signer) Sign(req *http.Request, body []byte) error {
+	now := s.cfg.Now().UTC()
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+	payloadHash := sha256Hex(body)
+	if s.cfg.UnsignedPayload {
+		payloadHash = "UNSIGNED-PAYLOAD"
+	}
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	if s.cfg.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", s.cfg.SessionToken)
+	}
+	host := req.Host
+	if host == "" {
+		host = req.URL.Host
+	}
+	// 参与签名的请求头：host、content-type以及所有x-amz-开头的请求头
+	headers := map[string]string{"host": host}
+	for key, values := range req.Header {
+		lower := strings.ToLower(key)
+		if lower == "content-type" || strings.HasPrefix(lower, "x-amz-") {
+			headers[lower] = strings.Join(values, ",")
+		}
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + strings.Join(strings.Fields(headers[name]), " ") + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		canonicalPath(req.URL),
+		canonicalQuery(req.URL.Query(), awsEscape),
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+	scope := strings.Join([]string{date, s.cfg.Region, s.cfg.Service, "aws4_request"}, "/")
+	stringToSign := strings.Join([]string{"AWS4-HMAC-SHA256", amzDate, scope, sha256Hex([]byte(canonicalRequest))}, "\n")
+	key := hmacSHA256([]byte("AWS4"+s.cfg.SecretAccessKey), date)
+	for _, part := range []string{s.cfg.Region, s.cfg.Service, "aws4_request"} {
+		key = hmacSHA256(key, part)
+	}
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s.cfg.AccessKeyID, scope, signedHeaders, signature))
+	return nil
+}
+
+func canonicalPath(u *url.URL) string {
+	if path := u.EscapedPath(); path != "" {
+		return path
+	}
+	return "/"
+}
+
+// canonicalQuery 按参数名排序，同名参数按参数值排序，escape为各签名方式要求的转义函数
+func canonicalQuery(values url.Values, escape func(string) string) string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	pairs := make([]string, 0, len(values))
+	for _, key := range keys {
+		vs := append([]string(nil), values[key]...)
+		sort.Strings(vs)
+		for _, v := range vs {
+			pairs = append(pairs, escape(key)+"="+escape(v))
+		}
+	}
+	return strings.Join(pairs, "&")
+}
+
+// awsEscape 按RFC 3986转义，只保留字母、数字和-_.~
+func awsEscape(s string) string {
+	return strings.ReplaceAll(url.QueryEscape(s), "+", "%20")
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+func newNonce() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
@@ -0,0 +1,349 @@
+package whttp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SSEEvent 一条Server-Sent Events事件，Data为data字段反序列化后的T类型对象
+type SSEEvent[T any] struct {
+	ID    string // 事件的id字段，未设置时为最近一次收到的id
+	Event string // 事件类型，未设置时为message
+	Data  T
+	Raw   string // data字段的原始内容，多行data使用换行符连接
+}
+
+// SSEError 服务端通过error事件返回的错误，对应wresp.WrapStreamHandler中writeStreamError写出的内容
+type SSEError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *SSEError) Error() string {
+	return fmt.Sprintf("sse error event, code: %d, message: %s", e.Code, e.Message)
+}
+
+// errNoContent 服务端返回204，按SSE规范表示不应再重连
+var errNoContent = errors.New("sse server responded 204 no content")
+
+type SSEClient[T any] interface {
+	WithContext(ctx context.Context) SSEClient[T]
+	WithBaseURL(baseURL string) SSEClient[T]
+	WithQueryParam(key, value string) SSEClient[T]
+	WithHeader(key, value string) SSEClient[T]
+	WithLastEventID(id string) SSEClient[T]
+	WithReconnect(maxRetries int, delay time.Duration) SSEClient[T]
+	Connect() (SSEStream[T], error)
+}
+
+// SSEStream 已建立的事件流，Events返回的通道在事件流结束后关闭，结束原因通过Err获取
+type SSEStream[T any] interface {
+	Events() <-chan SSEEvent[T]
+	Err() error
+	Close()
+}
+
+type sseClient[T any] struct {
+	ctx         context.Context
+	client      *Client
+	baseURL     string
+	queryParams url.Values
+	headers     map[string]string
+	lastEventID string
+	maxRetries  int           // 连接断开后连续重连失败的最大次数，负数表示不限制
+	retryDelay  time.Duration // 重连等待时间，服务端可以通过retry字段修改
+}
+
+func NewSSE[T any]() SSEClient[T] {
+	return SSE[T](DefaultClient)
+}
+
+// SSE 从Client派生一个SSE请求，继承Client的基础URL、默认请求头、认证和拦截器，不使用Client的超时时间和重试策略
+func SSE[T any](c *Client) SSEClient[T] {
+	if c == nil {
+		c = DefaultClient
+	}
+	headers := make(map[string]string, len(c.cfg.Headers))
+	for key, value := range c.cfg.Headers {
+		headers[key] = value
+	}
+	return &sseClient[T]{
+		ctx:         context.Background(),
+		client:      c,
+		baseURL:     c.cfg.BaseURL,
+		queryParams: url.Values{},
+		headers:     headers,
+		maxRetries:  -1,
+		retryDelay:  3 * time.Second,
+	}
+}
+
+// WithContext 设置事件流使用的上下文，上下文结束后事件流随之关闭
+func (s *sseClient[T]) WithContext(ctx context.Context) SSEClient[T] {
+	if ctx != nil {
+		s.ctx = ctx
+	}
+	return s
+}
+
+// WithBaseURL 设置事件流的URL地址，传入相对路径时拼接在Client配置的BaseURL之后
+func (s *sseClient[T]) WithBaseURL(baseURL string) SSEClient[T] {
+	s.baseURL = joinBaseURL(s.client.cfg.BaseURL, baseURL)
+	return s
+}
+
+// WithQueryParam 添加单个URL查询参数，value为空字符串时忽略
+func (s *sseClient[T]) WithQueryParam(key, value string) SSEClient[T] {
+	if value != "" {
+		s.queryParams.Set(key, value)
+	}
+	return s
+}
+
+// WithHeader 添加单个请求头，value为空字符串时忽略
+func (s *sseClient[T]) WithHeader(key, value string) SSEClient[T] {
+	if value != "" {
+		s.headers[key] = value
+	}
+	return s
+}
+
+// WithLastEventID 设置首次连接时携带的Last-Event-ID，用于从上次中断的位置继续接收事件
+func (s *sseClient[T]) WithLastEventID(id string) SSEClient[T] {
+	s.lastEventID = id
+	return s
+}
+
+// WithReconnect 设置断线重连策略，maxRetries为连续重连失败的最大次数，0表示不重连，负数表示不限制（默认）
+// delay为重连等待时间，默认3秒，服务端下发retry字段后以服务端为准
+func (s *sseClient[T]) WithReconnect(maxRetries int, delay time.Duration) SSEClient[T] {
+	s.maxRetries = maxRetries
+	if delay > 0 {
+		s.retryDelay = delay
+	}
+	return s
+}
+
+// Connect 建立连接，首次连接失败或返回非2xx状态码时直接返回错误，之后的断线在后台自动重连
+// 服务端返回204时按SSE规范视为没有事件，返回一个已经结束的事件流
+func (s *sseClient[T]) Connect() (SSEStream[T], error) {
+	ctx, cancel := context.WithCancel(s.ctx)
+	stream := &sseStream[T]{
+		client:      s,
+		ctx:         ctx,
+		cancel:      cancel,
+		events:      make(chan SSEEvent[T]),
+		lastEventID: s.lastEventID,
+		retryDelay:  s.retryDelay,
+	}
+	resp, err := stream.connect()
+	if errors.Is(err, errNoContent) {
+		cancel()
+		close(stream.events)
+		return stream, nil
+	}
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	go stream.run(resp)
+	return stream, nil
+}
+
+type sseStream[T any] struct {
+	client      *sseClient[T]
+	ctx         context.Context
+	cancel      context.CancelFunc
+	events      chan SSEEvent[T]
+	lastEventID string
+	retryDelay  time.Duration
+	errMu       sync.Mutex
+	err         error
+}
+
+func (s *sseStream[T]) Events() <-chan SSEEvent[T] {
+	return s.events
+}
+
+// Err 返回事件流结束的原因：放弃重连时为最后一次重连失败的错误，不重连时为连接中断的错误，
+// 服务端正常结束事件流且不重连、服务端返回204、调用Close或上下文结束导致的正常关闭返回nil
+func (s *sseStream[T]) Err() error {
+	s.errMu.Lock()
+	defer s.errMu.Unlock()
+	return s.err
+}
+
+func (s *sseStream[T]) Close() {
+	s.cancel()
+}
+
+func (s *sseStream[T]) connect() (*http.Response, error) {
+	if s.client.client.err != nil {
+		return nil, s.client.client.err
+	}
+	fullURL := s.client.baseURL
+	if len(s.client.queryParams) > 0 {
+		fullURL = fmt.Sprintf("%s?%s", fullURL, s.client.queryParams.Encode())
+	}
+	req, err := http.NewRequestWithContext(s.ctx, http.MethodGet, fullURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	for key, value := range s.client.headers {
+		req.Header.Set(key, value)
+	}
+	req.Header.Set("Accept", "text/event-stream")
+	req.Header.Set("Cache-Control", "no-cache")
+	if s.lastEventID != "" {
+		req.Header.Set("Last-Event-ID", s.lastEventID)
+	}
+	roundTrip := chainInterceptors(s.client.client.authRoundTrip(s.client.client.cfg.Auth, nil), s.client.client.snapshotInterceptors())
+	resp, err := roundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusNoContent {
+		_ = resp.Body.Close()
+		return nil, errNoContent
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		defer resp.Body.Close()
+		respBytes, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+		return nil, statusError(resp, respBytes, nil, nil)
+	}
+	return resp, nil
+}
+
+// run 读取事件直到事件流结束，连接断开时按重连策略重新连接
+func (s *sseStream[T]) run(resp *http.Response) {
+	defer close(s.events)
+	defer s.cancel()
+	failures := 0
+	for {
+		lastErr, err := s.read(resp.Body)
+		_ = resp.Body.Close()
+		if err != nil || s.ctx.Err() != nil {
+			s.setErr(err)
+			return
+		}
+		// 连接正常结束或中途断开，按重连策略重新连接，lastErr记录最近一次失败的原因，放弃重连时通过Err返回
+		for {
+			if s.client.maxRetries >= 0 && failures >= s.client.maxRetries {
+				s.setErr(lastErr)
+				return
+			}
+			failures++
+			if err = sleepWithContext(s.ctx, s.retryDelay); err != nil {
+				return
+			}
+			if resp, err = s.connect(); err == nil {
+				failures = 0
+				break
+			}
+			if errors.Is(err, errNoContent) || s.ctx.Err() != nil {
+				return
+			}
+			lastErr = err
+		}
+	}
+}
+
+// read 按SSE规范逐行解析事件，返回的第一个错误为连接中断的原因，正常结束时为nil，由调用方决定是否重连；
+// 第二个错误为收到error事件或反序列化失败时对应的错误，此时不再重连
+func (s *sseStream[T]) read(body io.Reader) (error, error) {
+	reader := bufio.NewReader(body)
+	var eventType string
+	var data strings.Builder
+	hasData := false
+	for {
+		line, err := reader.ReadString('\n')
+		if err == io.EOF {
+			return nil, nil
+		}
+		if err != nil {
+			// 读取中断（包括上下文取消）都视为连接断开，由调用方决定是否重连
+			return err, nil
+		}
+		line = strings.TrimRight(line, "\r\n")
+		if line == "" {
+			if hasData {
+				if err = s.dispatch(eventType, data.String()); err != nil {
+					return nil, err
+				}
+			}
+			eventType, hasData = "", false
+			data.Reset()
+			continue
+		}
+		if strings.HasPrefix(line, ":") {
+			continue // 注释行，一般用于心跳保活
+		}
+		field, value, _ := strings.Cut(line, ":")
+		value = strings.TrimPrefix(value, " ")
+		switch field {
+		case "event":
+			eventType = value
+		case "data":
+			if hasData {
+				data.WriteByte('\n')
+			}
+			data.WriteString(value)
+			hasData = true
+		case "id":
+			if !strings.ContainsRune(value, 0) {
+				s.lastEventID = value
+			}
+		case "retry":
+			if ms, err := strconv.Atoi(value); err == nil && ms >= 0 {
+				s.retryDelay = time.Duration(ms) * time.Millisecond
+			}
+		}
+	}
+}
+
+func (s *sseStream[T]) dispatch(eventType, raw string) error {
+	if eventType == "error" {
+		sseErr := &SSEError{Code: -1, Message: raw}
+		_ = json.Unmarshal([]byte(raw), sseErr)
+		return sseErr
+	}
+	if eventType == "" {
+		eventType = "message"
+	}
+	event := SSEEvent[T]{ID: s.lastEventID, Event: eventType, Raw: raw}
+	switch any(event.Data).(type) {
+	// T为string、[]byte或json.RawMessage时直接使用原始内容，不进行JSON反序列化
+	case string:
+		event.Data = any(raw).(T)
+	case []byte:
+		event.Data = any([]byte(raw)).(T)
+	case json.RawMessage:
+		event.Data = any(json.RawMessage(raw)).(T)
+	default:
+		if err := json.Unmarshal([]byte(raw), &event.Data); err != nil {
+			return fmt.Errorf("decode sse event data: %w", err)
+		}
+	}
+	select {
+	case s.events <- event:
+		return nil
+	case <-s.ctx.Done():
+		return nil
+	}
+}
+
+func (s *sseStream[T]) setErr(err error) {
+	s.errMu.Lock()
+	defer s.errMu.Unlock()
+	s.err = err
+}
@@ -0,0 +1,161 @@
+package whttp
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type sseMessage struct {
+	N int `json:"n"`
+}
+
+func collectEvents[T any](t *testing.T, stream SSEStream[T]) []SSEEvent[T] {
+	t.Helper()
+	var events []SSEEvent[T]
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case event, ok := <-stream.Events():
+			if !ok {
+				return events
+			}
+			events = append(events, event)
+		case <-timeout:
+			t.Fatal("event stream did not end")
+		}
+	}
+}
+
+func TestSSEReconnectWithLastEventID(t *testing.T) {
+	var mu sync.Mutex
+	var lastEventIDs []string
+	var connects atomic.Int32
+	srv := newJSONServer(t, func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		lastEventIDs = append(lastEventIDs, r.Header.Get("Last-Event-ID"))
+		mu.Unlock()
+		switch connects.Add(1) {
+		case 1:
+			w.Header().Set("Content-Type", "text/event-stream")
+			_, _ = fmt.Fprint(w, "retry: 10\n\n: heartbeat\n\nid: 1\ndata: {\"n\":1}\n\nid: 2\nevent: update\ndata: {\"n\":2}\n\n")
+		case 2:
+			w.Header().Set("Content-Type", "text/event-stream")
+			_, _ = fmt.Fprint(w, "id: 3\ndata: {\"n\":\ndata: 3}\n\n")
+		default:
+			// 按SSE规范，204表示不应再重连
+			w.WriteHeader(http.StatusNoContent)
+		}
+	})
+	stream, err := NewSSE[sseMessage]().WithBaseURL(srv.URL).WithReconnect(-1, time.Second).Connect()
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Now()
+	events := collectEvents(t, stream)
+	if err = stream.Err(); err != nil {
+		t.Fatalf("stream should end without error after 204, got %v", err)
+	}
+	// retry字段把重连间隔改为10毫秒
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Fatalf("reconnect took %v, retry field was ignored", elapsed)
+	}
+	if len(events) != 3 {
+		t.Fatalf("got %d events, want 3", len(events))
+	}
+	for i, event := range events {
+		if event.Data.N != i+1 || event.ID != fmt.Sprint(i+1) {
+			t.Fatalf("event %d: %+v", i, event)
+		}
+	}
+	if events[1].Event != "update" || events[0].Event != "message" {
+		t.Fatalf("unexpected event types %q %q", events[0].Event, events[1].Event)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if want := []string{"", "2", "3"}; fmt.Sprint(lastEventIDs) != fmt.Sprint(want) {
+		t.Fatalf("Last-Event-ID headers %q, want %q", lastEventIDs, want)
+	}
+}
+
+func TestSSEReportsLastReconnectError(t *testing.T) {
+	var connects atomic.Int32
+	srv := newJSONServer(t, func(w http.ResponseWriter, _ *http.Request) {
+		if connects.Add(1) == 1 {
+			w.Header().Set("Content-Type", "text/event-stream")
+			_, _ = fmt.Fprint(w, "data: {\"n\":1}\n\n")
+			return
+		}
+		w.WriteHeader(http.StatusUnauthorized)
+	})
+	stream, err := NewSSE[sseMessage]().WithBaseURL(srv.URL).WithReconnect(2, 10*time.Millisecond).Connect()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if events := collectEvents(t, stream); len(events) != 1 {
+		t.Fatalf("got %d events, want 1", len(events))
+	}
+	var httpErr *HTTPError
+	if !errors.As(stream.Err(), &httpErr) || httpErr.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("expected the 401 from the last reconnect, got %v", stream.Err())
+	}
+	if n := connects.Load(); n != 3 {
+		t.Fatalf("connected %d times, want 1 + 2 retries", n)
+	}
+}
+
+func TestSSEWithoutReconnectEndsCleanly(t *testing.T) {
+	var connects atomic.Int32
+	srv := newJSONServer(t, func(w http.ResponseWriter, _ *http.Request) {
+		connects.Add(1)
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, _ = fmt.Fprint(w, "data: hello\n\n")
+	})
+	stream, err := NewSSE[string]().WithBaseURL(srv.URL).WithReconnect(0, 10*time.Millisecond).Connect()
+	if err != nil {
+		t.Fatal(err)
+	}
+	events := collectEvents(t, stream)
+	if len(events) != 1 || events[0].Data != "hello" {
+		t.Fatalf("unexpected events %+v", events)
+	}
+	if err = stream.Err(); err != nil {
+		t.Fatalf("normal end of stream should not be an error, got %v", err)
+	}
+	if connects.Load() != 1 {
+		t.Fatalf("connected %d times, want 1", connects.Load())
+	}
+}
+
+func TestSSEErrorEvent(t *testing.T) {
+	srv := newJSONServer(t, func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, _ = fmt.Fprint(w, "event: error\ndata: {\"code\":1001,\"message\":\"bad\"}\n\n")
+	})
+	stream, err := NewSSE[sseMessage]().WithBaseURL(srv.URL).Connect()
+	if err != nil {
+		t.Fatal(err)
+	}
+	collectEvents(t, stream)
+	var sseErr *SSEError
+	if !errors.As(stream.Err(), &sseErr) || sseErr.Code != 1001 {
+		t.Fatalf("expected SSEError with code 1001, got %v", stream.Err())
+	}
+}
+
+func TestSSEConnectNoContent(t *testing.T) {
+	srv := newJSONServer(t, func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+	stream, err := NewSSE[sseMessage]().WithBaseURL(srv.URL).Connect()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if events := collectEvents(t, stream); len(events) != 0 || stream.Err() != nil {
+		t.Fatalf("expected an empty finished stream, got %d events, err %v", len(events), stream.Err())
+	}
+}
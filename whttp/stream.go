@@ -0,0 +1,100 @@
+package whttp
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+)
+
+// ErrResponseTooLarge 响应体超过WithMaxSize设置的大小
+var ErrResponseTooLarge = errors.New("response body exceeds max size")
+
+// handleStreamResponse 2xx状态码时把响应体流式写入w，过程中回调进度并检查大小和校验和，否则返回包含状态码的错误
+func (cli *httpClient[T]) handleStreamResponse(resp *http.Response, stats *requestStats, w io.Writer) (ResponseWrapper[T], error) {
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		// 错误响应体一般很小，这里限制读取大小，避免异常的大响应占用内存
+		respBytes, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+		if err != nil {
+			return nil, err
+		}
+		return nil, statusError(resp, respBytes, cli.errorType, cli.errorCodec(resp))
+	}
+	if cli.maxSize > 0 && resp.ContentLength > cli.maxSize {
+		return nil, fmt.Errorf("%w: content length %d, max %d", ErrResponseTooLarge, resp.ContentLength, cli.maxSize)
+	}
+	var body io.Reader = resp.Body
+	if cli.maxSize > 0 {
+		body = io.LimitReader(resp.Body, cli.maxSize+1) // 多读1个字节用于判断是否超过上限
+	}
+	writers := []io.Writer{w}
+	if cli.checksum != nil {
+		cli.checksum.Reset()
+		writers = append(writers, cli.checksum)
+	}
+	progress := &progressWriter{total: resp.ContentLength, callback: cli.progress}
+	writers = append(writers, progress)
+	written, err := io.Copy(io.MultiWriter(writers...), body)
+	if err != nil {
+		return nil, err
+	}
+	if cli.maxSize > 0 && written > cli.maxSize {
+		return nil, fmt.Errorf("%w: max %d", ErrResponseTooLarge, cli.maxSize)
+	}
+	if cli.checksum != nil {
+		actual := hex.EncodeToString(cli.checksum.Sum(nil))
+		if actual != cli.expectedSum {
+			return nil, fmt.Errorf("checksum mismatch: expected %s, got %s", cli.expectedSum, actual)
+		}
+	}
+	return newResponseWrapper[T](resp, stats), nil
+}
+
+// progressWriter 不写入任何数据，只统计字节数并回调进度
+type progressWriter struct {
+	written  int64
+	total    int64
+	callback func(written, total int64)
+}
+
+func (p *progressWriter) Write(b []byte) (int, error) {
+	p.written += int64(len(b))
+	if p.callback != nil {
+		p.callback(p.written, p.total)
+	}
+	return len(b), nil
+}
+
+// downloadFile 先写入同目录下的临时文件，成功后原子地重命名为filePath，失败时删除临时文件
+func downloadFile[T any](filePath string, sendTo func(w io.Writer) (ResponseWrapper[T], error)) (ResponseWrapper[T], error) {
+	tmpFile, err := os.CreateTemp(filepath.Dir(filePath), filepath.Base(filePath)+".*.tmp")
+	if err != nil {
+		return nil, err
+	}
+	tmpPath := tmpFile.Name()
+	success := false
+	defer func() {
+		if !success {
+			_ = tmpFile.Close()
+			_ = os.Remove(tmpPath)
+		}
+	}()
+	resp, err := sendTo(tmpFile)
+	if err != nil {
+		return nil, err
+	}
+	if err = tmpFile.Sync(); err != nil {
+		return nil, err
+	}
+	if err = tmpFile.Close(); err != nil {
+		return nil, err
+	}
+	if err = os.Rename(tmpPath, filePath); err != nil {
+		return nil, err
+	}
+	success = true
+	return resp, nil
+}
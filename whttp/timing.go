@@ -0,0 +1,115 @@
+package whttp
+
+import (
+	"context"
+	"crypto/tls"
+	"net/http/httptrace"
+	"sync"
+	"time"
+)
+
+// Timing 最后一次请求各阶段的耗时，复用连接时DNS、Connect、TLS为0
+type Timing struct {
+	DNS        time.Duration // DNS解析耗时
+	Connect    time.Duration // TCP建立连接耗时
+	TLS        time.Duration // TLS握手耗时
+	TTFB       time.Duration // 从开始获取连接到收到响应第一个字节的耗时，包含上面各阶段，不包含限流、签名等客户端内部的等待
+	ConnReused bool          // 是否复用了连接池中的连接
+}
+
+// timingTrace 通过httptrace记录单次请求的各阶段时间点，回调可能在不同的Goroutine中执行，需要加锁
+type timingTrace struct {
+	mu           sync.Mutex
+	start        time.Time
+	dnsStart     time.Time
+	connectStart time.Time
+	tlsStart     time.Time
+	timing       Timing
+}
+
+func newTimingTrace() *timingTrace {
+	return &timingTrace{}
+}
+
+func (t *timingTrace) clientTrace() *httptrace.ClientTrace {
+	return &httptrace.ClientTrace{
+		// 收到401后重新认证时同一个trace会再次发送请求，每次获取连接时重新计时，记录的是最后一次发送
+		GetConn: func(string) {
+			t.mu.Lock()
+			t.start = time.Now()
+			t.timing = Timing{}
+			t.mu.Unlock()
+		},
+		DNSStart: func(httptrace.DNSStartInfo) {
+			t.mu.Lock()
+			t.dnsStart = time.Now()
+			t.mu.Unlock()
+		},
+		DNSDone: func(httptrace.DNSDoneInfo) {
+			t.mu.Lock()
+			t.timing.DNS = time.Since(t.dnsStart)
+			t.mu.Unlock()
+		},
+		ConnectStart: func(string, string) {
+			t.mu.Lock()
+			t.connectStart = time.Now()
+			t.mu.Unlock()
+		},
+		ConnectDone: func(string, string, error) {
+			t.mu.Lock()
+			t.timing.Connect = time.Since(t.connectStart)
+			t.mu.Unlock()
+		},
+		TLSHandshakeStart: func() {
+			t.mu.Lock()
+			t.tlsStart = time.Now()
+			t.mu.Unlock()
+		},
+		TLSHandshakeDone: func(tls.ConnectionState, error) {
+			t.mu.Lock()
+			t.timing.TLS = time.Since(t.tlsStart)
+			t.mu.Unlock()
+		},
+		GotConn: func(info httptrace.GotConnInfo) {
+			t.mu.Lock()
+			t.timing.ConnReused = info.Reused
+			t.mu.Unlock()
+		},
+		GotFirstResponseByte: func() {
+			t.mu.Lock()
+			t.timing.TTFB = time.Since(t.start)
+			t.mu.Unlock()
+		},
+	}
+}
+
+func (t *timingTrace) result() Timing {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.timing
+}
+
+// withoutClientTrace 屏蔽上下文中的httptrace.ClientTrace，认证等内部发起的请求使用业务请求的上下文时，
+// 不会把自己的连接和耗时记录到业务请求的Timing中
+func withoutClientTrace(ctx context.Context) context.Context {
+	return noTraceContext{ctx}
+}
+
+type noTraceContext struct {
+	context.Context
+}
+
+func (c noTraceContext) Value(key any) any {
+	value := c.Context.Value(key)
+	if _, ok := value.(*httptrace.ClientTrace); ok {
+		return nil
+	}
+	return value
+}
+
+// requestStats 一次Send调用的统计信息，跨越所有重试
+type requestStats struct {
+	start    time.Time
+	attempts int
+	trace    *timingTrace // 最后一次请求的耗时记录
+}
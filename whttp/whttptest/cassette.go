@@ -0,0 +1,234 @@
+package whttptest
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"unicode/utf8"
+)
+
+// Mode 录制回放Transport的工作模式
+type Mode int
+
+const (
+	ModeReplayOrRecord Mode = iota // 磁带文件存在时回放，否则发送真实请求并录制
+	ModeReplay                     // 只回放，找不到匹配的记录时返回ErrNoInteraction
+	ModeRecord                     // 总是发送真实请求，并覆盖已有的磁带文件
+)
+
+// ErrNoInteraction 回放时磁带中没有与请求匹配的记录
+var ErrNoInteraction = errors.New("whttptest: no matching interaction in cassette")
+
+// Interaction 磁带中的一次请求和响应
+type Interaction struct {
+	Request  RecordedRequest  `json:"request"`
+	Response RecordedResponse `json:"response"`
+}
+
+type RecordedRequest struct {
+	Method string      `json:"method"`
+	URL    string      `json:"url"`
+	Header http.Header `json:"header,omitempty"`
+	Body   Body        `json:"body,omitempty"`
+}
+
+type RecordedResponse struct {
+	StatusCode int         `json:"status_code"`
+	Header     http.Header `json:"header,omitempty"`
+	Body       Body        `json:"body,omitempty"`
+}
+
+// Body 录制的请求体或响应体，UTF-8文本按原样保存以便阅读和修改，二进制内容使用Base64编码保存
+type Body []byte
+
+func (b Body) MarshalJSON() ([]byte, error) {
+	if utf8.Valid(b) {
+		return json.Marshal(string(b))
+	}
+	return json.Marshal(map[string]string{"base64": base64.StdEncoding.EncodeToString(b)})
+}
+
+func (b *Body) UnmarshalJSON(data []byte) error {
+	var text string
+	if err := json.Unmarshal(data, &text); err == nil {
+		*b = Body(text)
+		return nil
+	}
+	var encoded struct {
+		Base64 string `json:"base64"`
+	}
+	if err := json.Unmarshal(data, &encoded); err != nil {
+		return err
+	}
+	decoded, err := base64.StdEncoding.DecodeString(encoded.Base64)
+	*b = decoded
+	return err
+}
+
+// RecorderConfig 录制回放Transport的配置
+type RecorderConfig struct {
+	Path          string            // 磁带文件路径
+	Mode          Mode              // 工作模式，默认ModeReplayOrRecord
+	Transport     http.RoundTripper // 录制时发送真实请求的Transport，默认http.DefaultTransport
+	RedactHeaders []string          // 录制时不保存的请求头，默认Authorization、Cookie
+	// 判断请求是否与记录匹配，默认比较Method、URL和请求体
+	Match func(req *http.Request, body []byte, recorded *RecordedRequest) bool
+}
+
+// Recorder 录制回放Transport，通过whttp.ClientConfig.Transport接入
+// 回放时同一个请求的多条记录按录制顺序依次返回
+type Recorder struct {
+	cfg          RecorderConfig
+	replaying    bool
+	mu           sync.Mutex
+	interactions []*Interaction
+	used         []bool
+}
+
+// NewRecorder 创建录制回放Transport，回放模式下读取磁带文件，录制的内容在调用Stop时写入磁带文件
+func NewRecorder(cfg RecorderConfig) (*Recorder, error) {
+	if cfg.Transport == nil {
+		cfg.Transport = http.DefaultTransport
+	}
+	if cfg.RedactHeaders == nil {
+		cfg.RedactHeaders = []string{"Authorization", "Cookie"}
+	}
+	if cfg.Match == nil {
+		cfg.Match = defaultMatch
+	}
+	r := &Recorder{cfg: cfg}
+	switch cfg.Mode {
+	case ModeRecord:
+		return r, nil
+	case ModeReplayOrRecord:
+		if _, err := os.Stat(cfg.Path); errors.Is(err, os.ErrNotExist) {
+			return r, nil
+		}
+	}
+	data, err := os.ReadFile(cfg.Path)
+	if err != nil {
+		return nil, fmt.Errorf("whttptest: read cassette: %w", err)
+	}
+	if err = json.Unmarshal(data, &r.interactions); err != nil {
+		return nil, fmt.Errorf("whttptest: decode cassette %s: %w", cfg.Path, err)
+	}
+	r.replaying = true
+	r.used = make([]bool, len(r.interactions))
+	return r, nil
+}
+
+// Replaying 返回当前是否处于回放状态
+func (r *Recorder) Replaying() bool {
+	return r.replaying
+}
+
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil && req.Body != http.NoBody {
+		var err error
+		body, err = io.ReadAll(req.Body)
+		_ = req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+	if r.replaying {
+		return r.replay(req, body)
+	}
+	return r.record(req, body)
+}
+
+func (r *Recorder) replay(req *http.Request, body []byte) (*http.Response, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i, interaction := range r.interactions {
+		if r.used[i] || !r.cfg.Match(req, body, &interaction.Request) {
+			continue
+		}
+		r.used[i] = true
+		return &http.Response{
+			Status:        fmt.Sprintf("%d %s", interaction.Response.StatusCode, http.StatusText(interaction.Response.StatusCode)),
+			StatusCode:    interaction.Response.StatusCode,
+			Proto:         "HTTP/1.1",
+			ProtoMajor:    1,
+			ProtoMinor:    1,
+			Header:        interaction.Response.Header.Clone(),
+			Body:          io.NopCloser(bytes.NewReader(interaction.Response.Body)),
+			ContentLength: int64(len(interaction.Response.Body)),
+			Request:       req,
+		}, nil
+	}
+	return nil, fmt.Errorf("%w: %s %s", ErrNoInteraction, req.Method, req.URL)
+}
+
+func (r *Recorder) record(req *http.Request, body []byte) (*http.Response, error) {
+	outReq := req.Clone(req.Context())
+	if body != nil {
+		outReq.Body = io.NopCloser(bytes.NewReader(body))
+	}
+	resp, err := r.cfg.Transport.RoundTrip(outReq)
+	if err != nil {
+		return nil, err
+	}
+	respBody, err := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(respBody))
+	header := req.Header.Clone()
+	for _, key := range r.cfg.RedactHeaders {
+		header.Del(key)
+	}
+	r.mu.Lock()
+	r.interactions = append(r.interactions, &Interaction{
+		Request:  RecordedRequest{Method: req.Method, URL: req.URL.String(), Header: header, Body: body},
+		Response: RecordedResponse{StatusCode: resp.StatusCode, Header: resp.Header.Clone(), Body: respBody},
+	})
+	r.mu.Unlock()
+	return resp, nil
+}
+
+// Stop 录制状态下把录制的内容写入磁带文件，回放状态下不做任何操作
+func (r *Recorder) Stop() error {
+	if r.replaying {
+		return nil
+	}
+	r.mu.Lock()
+	data, err := json.MarshalIndent(r.interactions, "", "  ")
+	r.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	if err = os.MkdirAll(filepath.Dir(r.cfg.Path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(r.cfg.Path, data, 0o644)
+}
+
+func defaultMatch(req *http.Request, body []byte, recorded *RecordedRequest) bool {
+	return req.Method == recorded.Method && req.URL.String() == recorded.URL && bytes.Equal(body, recorded.Body)
+}
+
+// Record 在测试中创建录制回放Transport，创建失败时终止测试，测试结束时自动调用Stop保存磁带
+func Record(t testing.TB, cfg RecorderConfig) *Recorder {
+	t.Helper()
+	r, err := NewRecorder(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if err := r.Stop(); err != nil {
+			t.Errorf("whttptest: save cassette: %v", err)
+		}
+	})
+	return r
+}
@@ -0,0 +1,187 @@
+package whttptest
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/mundo-wang/wtool/whttp"
+)
+
+var binaryPayload = []byte{0x00, 0xff, 0xfe, 0x80, 0x01}
+
+func TestBodyJSONRoundTrip(t *testing.T) {
+	cases := []struct {
+		body       Body
+		wantBase64 bool
+	}{
+		{Body("readable text"), false},
+		{Body(binaryPayload), true},
+	}
+	for _, c := range cases {
+		data, err := json.Marshal(c.body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if bytes.Contains(data, []byte(`"base64"`)) != c.wantBase64 {
+			t.Fatalf("unexpected encoding %s", data)
+		}
+		var decoded Body
+		if err = json.Unmarshal(data, &decoded); err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(decoded, c.body) {
+			t.Fatalf("round trip %q, want %q", decoded, c.body)
+		}
+	}
+}
+
+// upstream 每次调用/counter返回递增的序号，/gzip按Accept-Encoding压缩响应，/binary原样返回请求体
+func upstream(t *testing.T) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+	var counter atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/counter":
+			_, _ = fmt.Fprintf(w, "call-%d", counter.Add(1))
+		case "/gzip":
+			// 与真实服务端一样，客户端声明接受gzip时压缩响应
+			if !strings.Contains(r.Header.Get("Accept-Encoding"), "gzip") {
+				_, _ = w.Write([]byte("plain text"))
+				return
+			}
+			w.Header().Set("Content-Encoding", "gzip")
+			zw := gzip.NewWriter(w)
+			_, _ = zw.Write([]byte("plain text"))
+			_ = zw.Close()
+		case "/binary":
+			body, _ := io.ReadAll(r.Body)
+			w.Header().Set("Content-Type", "application/octet-stream")
+			_, _ = w.Write(body)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &counter
+}
+
+func TestRecorderRecordAndReplay(t *testing.T) {
+	srv, counter := upstream(t)
+	path := filepath.Join(t.TempDir(), "cassettes", "demo.json")
+
+	recorder, err := NewRecorder(RecorderConfig{Path: path})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if recorder.Replaying() {
+		t.Fatal("recorder should record when the cassette does not exist")
+	}
+	client := whttp.NewClient(whttp.ClientConfig{BaseURL: srv.URL, Transport: recorder})
+	for i := 1; i <= 2; i++ {
+		resp, err := whttp.Get[[]byte](client).WithBaseURL("/counter").WithHeader("Authorization", "Bearer secret").Send()
+		if err != nil || string(resp.GetRespData()) != fmt.Sprintf("call-%d", i) {
+			t.Fatalf("record call %d: %v", i, err)
+		}
+	}
+	resp, err := whttp.Post[[]byte](client).WithBaseURL("/binary").WithBody(bytesCodec{}, binaryPayload).Send()
+	if err != nil || !bytes.Equal(resp.GetRespData(), binaryPayload) {
+		t.Fatalf("record binary: %v", err)
+	}
+	if err = recorder.Stop(); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cassette := string(data)
+	if !strings.Contains(cassette, `"call-1"`) || !strings.Contains(cassette, `"base64"`) {
+		t.Fatalf("text bodies should be readable and binary bodies base64 encoded:\n%s", cassette)
+	}
+	if strings.Contains(cassette, "Bearer secret") {
+		t.Fatal("Authorization header should be redacted")
+	}
+
+	// 回放时不再访问真实服务，同一个请求的多条记录按录制顺序返回
+	srv.Close()
+	replayer, err := NewRecorder(RecorderConfig{Path: path, Mode: ModeReplay})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !replayer.Replaying() {
+		t.Fatal("recorder should replay an existing cassette")
+	}
+	client = whttp.NewClient(whttp.ClientConfig{BaseURL: srv.URL, Transport: replayer})
+	for i := 1; i <= 2; i++ {
+		resp, err := whttp.Get[[]byte](client).WithBaseURL("/counter").Send()
+		if err != nil || string(resp.GetRespData()) != fmt.Sprintf("call-%d", i) {
+			t.Fatalf("replay call %d: %v", i, err)
+		}
+	}
+	resp, err = whttp.Post[[]byte](client).WithBaseURL("/binary").WithBody(bytesCodec{}, binaryPayload).Send()
+	if err != nil || !bytes.Equal(resp.GetRespData(), binaryPayload) {
+		t.Fatalf("replay binary: %v", err)
+	}
+	if _, err = whttp.Get[[]byte](client).WithBaseURL("/counter").Send(); !errors.Is(err, ErrNoInteraction) {
+		t.Fatalf("expected ErrNoInteraction after all records are used, got %v", err)
+	}
+	if counter.Load() != 2 {
+		t.Fatalf("upstream called %d times, want 2", counter.Load())
+	}
+}
+
+func TestRecorderStoresReadableCompressedResponses(t *testing.T) {
+	srv, _ := upstream(t)
+	path := filepath.Join(t.TempDir(), "gzip.json")
+	recorder, err := NewRecorder(RecorderConfig{Path: path})
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := whttp.NewClient(whttp.ClientConfig{BaseURL: srv.URL, Transport: recorder})
+	resp, err := whttp.Get[[]byte](client).WithBaseURL("/gzip").Send()
+	if err != nil || string(resp.GetRespData()) != "plain text" {
+		t.Fatalf("record: %v", err)
+	}
+	if err = recorder.Stop(); err != nil {
+		t.Fatal(err)
+	}
+	var interactions []Interaction
+	data, _ := os.ReadFile(path)
+	if err = json.Unmarshal(data, &interactions); err != nil {
+		t.Fatal(err)
+	}
+	recorded := interactions[0]
+	if string(recorded.Response.Body) != "plain text" || recorded.Response.Header.Get("Content-Encoding") != "" {
+		t.Fatalf("response should be stored decompressed:\n%s", data)
+	}
+	if recorded.Request.Header.Get("Accept-Encoding") != "" {
+		t.Fatalf("client should not force Accept-Encoding on a custom transport:\n%s", data)
+	}
+}
+
+func TestRecorderReplayMissingCassette(t *testing.T) {
+	_, err := NewRecorder(RecorderConfig{Path: filepath.Join(t.TempDir(), "missing.json"), Mode: ModeReplay})
+	if err == nil {
+		t.Fatal("replay mode should fail without a cassette")
+	}
+}
+
+// bytesCodec 把字节数组原样作为请求体
+type bytesCodec struct{}
+
+func (bytesCodec) ContentType() string                   { return "application/octet-stream" }
+func (bytesCodec) Marshal(v interface{}) ([]byte, error) { return v.([]byte), nil }
+func (bytesCodec) Unmarshal(data []byte, v interface{}) error {
+	*v.(*[]byte) = data
+	return nil
+}
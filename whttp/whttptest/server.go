@@ -0,0 +1,253 @@
+// Package whttptest 为使用whttp的代码提供测试工具：可链式配置的模拟服务端，以及录制回放真实请求的Transport
+package whttptest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/mundo-wang/wtool/whttp"
+)
+
+// Server 模拟服务端，按注册顺序匹配路由，没有匹配的路由时返回404并使测试失败
+// 测试结束时自动关闭，并检查通过Times设置的调用次数
+type Server struct {
+	t      testing.TB
+	server *httptest.Server
+	mu     sync.Mutex
+	routes []*Route
+}
+
+// NewServer 创建并启动模拟服务端
+func NewServer(t testing.TB) *Server {
+	t.Helper()
+	s := &Server{t: t}
+	s.server = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	t.Cleanup(func() {
+		s.server.Close()
+		s.AssertExpectations()
+	})
+	return s
+}
+
+// URL 返回模拟服务端的地址，如http://127.0.0.1:12345
+func (s *Server) URL() string {
+	return s.server.URL
+}
+
+// Client 返回以模拟服务端地址为BaseURL的whttp.Client，cfg中的BaseURL会被覆盖
+func (s *Server) Client(cfg whttp.ClientConfig) *whttp.Client {
+	cfg.BaseURL = s.server.URL
+	return whttp.NewClient(cfg)
+}
+
+// On 注册一个路由，path不包含查询参数
+func (s *Server) On(method, path string) *Route {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	route := &Route{
+		mu:      &s.mu,
+		method:  method,
+		path:    path,
+		query:   map[string]string{},
+		headers: map[string]string{},
+		status:  http.StatusOK,
+		header:  http.Header{},
+		times:   -1,
+	}
+	s.routes = append(s.routes, route)
+	return route
+}
+
+// AssertExpectations 检查所有设置了Times的路由是否被调用了指定次数
+func (s *Server) AssertExpectations() {
+	s.t.Helper()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, route := range s.routes {
+		if route.times >= 0 && route.calls != route.times {
+			s.t.Errorf("whttptest: %s %s expected %d calls, got %d", route.method, route.path, route.times, route.calls)
+		}
+	}
+}
+
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		s.t.Errorf("whttptest: read request body: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	s.mu.Lock()
+	var matched *Route
+	for _, route := range s.routes {
+		if route.match(r, body) {
+			matched = route
+			route.calls++
+			break
+		}
+	}
+	s.mu.Unlock()
+	if matched == nil {
+		s.t.Errorf("whttptest: no route matched %s %s", r.Method, r.URL.RequestURI())
+		http.NotFound(w, r)
+		return
+	}
+	matched.reply(w, r)
+}
+
+// Route 模拟服务端的一个路由，通过链式调用设置匹配条件和响应内容
+type Route struct {
+	mu       *sync.Mutex // 所属Server的锁，保护calls
+	method   string
+	path     string
+	query    map[string]string
+	headers  map[string]string
+	body     []byte
+	jsonBody interface{}
+	status   int
+	header   http.Header
+	respBody []byte
+	handler  http.HandlerFunc
+	times    int // 期望的调用次数，负数表示不检查
+	calls    int
+}
+
+// WithQuery 要求查询参数key的值为value
+func (r *Route) WithQuery(key, value string) *Route {
+	r.query[key] = value
+	return r
+}
+
+// WithHeader 要求请求头key的值为value
+func (r *Route) WithHeader(key, value string) *Route {
+	r.headers[key] = value
+	return r
+}
+
+// WithBody 要求请求体与body完全相同
+func (r *Route) WithBody(body string) *Route {
+	r.body = []byte(body)
+	return r
+}
+
+// WithJSONBody 要求请求体反序列化后与v序列化后的JSON语义相同，不比较字段顺序和空白
+func (r *Route) WithJSONBody(v interface{}) *Route {
+	r.jsonBody = v
+	return r
+}
+
+// Reply 设置响应状态码和响应体
+func (r *Route) Reply(status int, body string) *Route {
+	r.status = status
+	r.respBody = []byte(body)
+	return r
+}
+
+// ReplyJSON 设置响应状态码，并把v序列化为JSON作为响应体
+func (r *Route) ReplyJSON(status int, v interface{}) *Route {
+	data, err := json.Marshal(v)
+	if err != nil {
+		panic(fmt.Sprintf("whttptest: marshal reply: %v", err))
+	}
+	r.header.Set("Content-Type", "application/json")
+	return r.Reply(status, string(data))
+}
+
+// ReplyFile 设置响应状态码，并使用fixture文件的内容作为响应体
+func (r *Route) ReplyFile(status int, filePath string) *Route {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		panic(fmt.Sprintf("whttptest: read fixture: %v", err))
+	}
+	return r.Reply(status, string(data))
+}
+
+// ReplyHeader 设置响应头
+func (r *Route) ReplyHeader(key, value string) *Route {
+	r.header.Set(key, value)
+	return r
+}
+
+// ReplyFunc 使用自定义处理函数生成响应，设置后Reply系列方法不再生效
+func (r *Route) ReplyFunc(handler http.HandlerFunc) *Route {
+	r.handler = handler
+	return r
+}
+
+// Times 设置期望的调用次数，测试结束时检查
+func (r *Route) Times(n int) *Route {
+	r.times = n
+	return r
+}
+
+// Calls 返回该路由已被调用的次数
+func (r *Route) Calls() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.calls
+}
+
+func (r *Route) match(req *http.Request, body []byte) bool {
+	if !strings.EqualFold(r.method, req.Method) || r.path != req.URL.Path {
+		return false
+	}
+	query := req.URL.Query()
+	for key, value := range r.query {
+		if query.Get(key) != value {
+			return false
+		}
+	}
+	for key, value := range r.headers {
+		if req.Header.Get(key) != value {
+			return false
+		}
+	}
+	if r.body != nil && !bytes.Equal(r.body, body) {
+		return false
+	}
+	if r.jsonBody != nil {
+		return jsonEqual(r.jsonBody, body)
+	}
+	return true
+}
+
+func (r *Route) reply(w http.ResponseWriter, req *http.Request) {
+	if r.handler != nil {
+		r.handler(w, req)
+		return
+	}
+	for key, values := range r.header {
+		w.Header()[key] = values
+	}
+	w.WriteHeader(r.status)
+	_, _ = w.Write(r.respBody)
+}
+
+func jsonEqual(expected interface{}, body []byte) bool {
+	data, err := json.Marshal(expected)
+	if err != nil {
+		return false
+	}
+	var want, got interface{}
+	if json.Unmarshal(data, &want) != nil || json.Unmarshal(body, &got) != nil {
+		return false
+	}
+	return reflect.DeepEqual(want, got)
+}
+
+// UseDefaultClient 在测试期间把whttp.DefaultClient替换为c，使NewGet等函数创建的请求也经过c，测试结束时恢复
+// 替换的是全局变量，使用它的测试不能并行执行
+func UseDefaultClient(t testing.TB, c *whttp.Client) {
+	original := whttp.DefaultClient
+	whttp.DefaultClient = c
+	t.Cleanup(func() { whttp.DefaultClient = original })
+}
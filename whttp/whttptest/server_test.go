@@ -0,0 +1,156 @@
+package whttptest
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/mundo-wang/wtool/whttp"
+)
+
+// recordingTB 记录Errorf的内容而不使测试失败，用于检查Server报告的错误
+type recordingTB struct {
+	testing.TB
+	mu       sync.Mutex
+	errs     []string
+	cleanups []func()
+}
+
+func (r *recordingTB) Helper() {}
+
+func (r *recordingTB) Errorf(format string, args ...any) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.errs = append(r.errs, fmt.Sprintf(format, args...))
+}
+
+func (r *recordingTB) Cleanup(f func()) {
+	r.cleanups = append(r.cleanups, f)
+}
+
+func (r *recordingTB) runCleanups() {
+	for i := len(r.cleanups) - 1; i >= 0; i-- {
+		r.cleanups[i]()
+	}
+}
+
+func (r *recordingTB) errors() string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return strings.Join(r.errs, "\n")
+}
+
+// textCodec 把字符串原样作为请求体
+type textCodec struct{}
+
+func (textCodec) ContentType() string                   { return "text/plain" }
+func (textCodec) Marshal(v interface{}) ([]byte, error) { return []byte(v.(string)), nil }
+func (textCodec) Unmarshal(data []byte, v interface{}) error {
+	*v.(*string) = string(data)
+	return nil
+}
+
+type user struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+func TestServerMatching(t *testing.T) {
+	s := NewServer(t)
+	// 按注册顺序匹配，条件更严格的路由需要先注册
+	s.On(http.MethodGet, "/users").WithQuery("id", "1").ReplyJSON(http.StatusOK, user{ID: 1, Name: "first"})
+	s.On(http.MethodGet, "/users").ReplyJSON(http.StatusOK, user{Name: "any"})
+	s.On(http.MethodPost, "/users").WithHeader("X-Token", "secret").WithJSONBody(map[string]any{"name": "new"}).
+		ReplyHeader("Location", "/users/2").ReplyJSON(http.StatusCreated, user{ID: 2, Name: "new"})
+	s.On(http.MethodPut, "/raw").WithBody("exact").Reply(http.StatusOK, "ok")
+	client := s.Client(whttp.ClientConfig{})
+
+	resp, err := whttp.Get[user](client).WithBaseURL("/users").WithQueryParam("id", "1").Send()
+	if err != nil || resp.GetRespData().Name != "first" {
+		t.Fatalf("query route: %+v, %v", resp, err)
+	}
+	resp, err = whttp.Get[user](client).WithBaseURL("/users").WithQueryParam("id", "9").Send()
+	if err != nil || resp.GetRespData().Name != "any" {
+		t.Fatalf("fallback route: %+v, %v", resp, err)
+	}
+	// JSON请求体按语义比较，与字段顺序和空白无关
+	resp, err = whttp.Post[user](client).WithBaseURL("/users").WithHeader("X-Token", "secret").
+		WithBody(whttp.JSONCodec, map[string]string{"name": "new"}).Send()
+	if err != nil || resp.GetStatusCode() != http.StatusCreated || resp.GetRespHeader("Location") != "/users/2" {
+		t.Fatalf("json body route: %+v, %v", resp, err)
+	}
+	raw, err := whttp.Put[[]byte](client).WithBaseURL("/raw").WithBody(textCodec{}, "exact").Send()
+	if err != nil || string(raw.GetRespData()) != "ok" {
+		t.Fatalf("exact body route: %v", err)
+	}
+}
+
+func TestServerUnmatchedRequest(t *testing.T) {
+	tb := &recordingTB{TB: t}
+	s := NewServer(tb)
+	defer tb.runCleanups()
+	s.On(http.MethodPost, "/users").WithHeader("X-Token", "secret").Reply(http.StatusOK, "")
+
+	_, err := whttp.Post[[]byte](s.Client(whttp.ClientConfig{})).WithBaseURL("/users").Send()
+	var httpErr *whttp.HTTPError
+	if !errors.As(err, &httpErr) || httpErr.StatusCode != http.StatusNotFound {
+		t.Fatalf("expected 404, got %v", err)
+	}
+	if !strings.Contains(tb.errors(), "no route matched POST /users") {
+		t.Fatalf("unexpected errors: %q", tb.errors())
+	}
+}
+
+func TestServerTimes(t *testing.T) {
+	tb := &recordingTB{TB: t}
+	s := NewServer(tb)
+	once := s.On(http.MethodGet, "/once").Reply(http.StatusOK, "").Times(1)
+	twice := s.On(http.MethodGet, "/twice").Reply(http.StatusOK, "").Times(2)
+	s.On(http.MethodGet, "/unchecked").Reply(http.StatusOK, "")
+	client := s.Client(whttp.ClientConfig{})
+	for _, path := range []string{"/once", "/twice"} {
+		if _, err := whttp.Get[[]byte](client).WithBaseURL(path).Send(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if once.Calls() != 1 || twice.Calls() != 1 {
+		t.Fatalf("calls %d and %d, want 1 and 1", once.Calls(), twice.Calls())
+	}
+	tb.runCleanups()
+	if got := tb.errors(); got != "whttptest: GET /twice expected 2 calls, got 1" {
+		t.Fatalf("unexpected errors: %q", got)
+	}
+}
+
+func TestServerReplyFunc(t *testing.T) {
+	s := NewServer(t)
+	s.On(http.MethodGet, "/echo").ReplyFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(r.URL.Query().Get("q")))
+	})
+	resp, err := whttp.Get[[]byte](s.Client(whttp.ClientConfig{})).WithBaseURL("/echo").WithQueryParam("q", "hi").Send()
+	if err != nil || string(resp.GetRespData()) != "hi" {
+		t.Fatalf("got %v", err)
+	}
+}
+
+func TestRouteCallsConcurrent(t *testing.T) {
+	s := NewServer(t)
+	route := s.On(http.MethodGet, "/ping").Reply(http.StatusOK, "").Times(20)
+	client := s.Client(whttp.ClientConfig{})
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, _ = whttp.Get[[]byte](client).WithBaseURL("/ping").Send()
+			_ = route.Calls()
+		}()
+	}
+	wg.Wait()
+	if route.Calls() != 20 {
+		t.Fatalf("calls %d, want 20", route.Calls())
+	}
+}
@@ -0,0 +1,356 @@
+package wlog
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// AuditConfig 审计日志的配置，审计日志写入独立的文件，不会与普通日志混在一起
+type AuditConfig struct {
+	Path string // 审计日志文件路径，文件已存在时在末尾续写，并延续其中的序号和哈希链
+	Key  []byte // 计算HMAC-SHA256使用的密钥，校验审计文件时需要使用同一个密钥
+	// 文件末尾存在不完整的行（一般是写入过程中进程崩溃）时，默认InitAudit返回ErrAuditPartialLine拒绝启动；
+	// 设置为true时把不完整的内容移动到Path.partial-<时间戳>文件中保留，截断原文件后从最后一个完整行继续
+	RepairPartialLine bool
+}
+
+type auditWriter struct {
+	mu      sync.Mutex
+	file    *os.File
+	key     []byte
+	seq     uint64
+	prevMAC string
+}
+
+var (
+	auditMu sync.RWMutex
+	auditor *auditWriter
+)
+
+var ErrAuditNotInitialized = errors.New("wlog: audit log is not initialized")
+
+// ErrAuditPartialLine 审计文件以不完整的行结尾，可以通过errors.Is判断
+var ErrAuditPartialLine = errors.New("wlog: audit file ends with an incomplete line")
+
+// AuditPartialLineError 审计文件末尾不完整的行的位置，Offset为该行在文件中的起始位置
+type AuditPartialLineError struct {
+	Path   string
+	Offset int64
+	Size   int
+}
+
+func (e *AuditPartialLineError) Error() string {
+	return fmt.Sprintf("wlog: audit file %s ends with an incomplete line (%d bytes at offset %d), "+
+		"the process may have crashed while writing, set AuditConfig.RepairPartialLine to move it aside", e.Path, e.Size, e.Offset)
+}
+
+func (e *AuditPartialLineError) Is(target error) bool {
+	return target == ErrAuditPartialLine
+}
+
+// InitAudit 打开审计日志文件，之后才能通过Audit记录审计日志，重复调用会关闭之前打开的文件
+func InitAudit(cfg AuditConfig) error {
+	if cfg.Path == "" || len(cfg.Key) == 0 {
+		return errors.New("wlog: audit path and key are required")
+	}
+	w := &auditWriter{key: cfg.Key}
+	// 读取已有文件的最后一行，保证进程重启后序号和哈希链是连续的
+	if f, err := os.Open(cfg.Path); err == nil {
+		last, partial, err := lastAuditLine(f)
+		_ = f.Close()
+		if err != nil {
+			return err
+		}
+		if partial != nil {
+			if !cfg.RepairPartialLine {
+				return partial
+			}
+			if err = repairPartialLine(cfg.Path, partial); err != nil {
+				return err
+			}
+		}
+		if last != nil {
+			payload, mac, err := splitAuditLine(last)
+			if err != nil {
+				return err
+			}
+			var record auditRecord
+			if err = json.Unmarshal(payload, &record); err != nil {
+				return fmt.Errorf("wlog: invalid audit line: %w", err)
+			}
+			w.seq = record.Seq
+			w.prevMAC = mac
+		}
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+	f, err := os.OpenFile(cfg.Path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return err
+	}
+	w.file = f
+	auditMu.Lock()
+	defer auditMu.Unlock()
+	if auditor != nil {
+		_ = auditor.close()
+	}
+	auditor = w
+	return nil
+}
+
+// CloseAudit 关闭审计日志文件
+func CloseAudit() error {
+	auditMu.Lock()
+	defer auditMu.Unlock()
+	if auditor == nil {
+		return nil
+	}
+	err := auditor.close()
+	auditor = nil
+	return err
+}
+
+type AuditEntry interface {
+	Field(key string, value interface{}) AuditEntry
+	Err(err error) AuditEntry
+	Record() error
+}
+
+type auditEntry struct {
+	ctx    context.Context
+	action string
+	fields map[string]interface{}
+	err    error
+}
+
+// Audit 创建一条审计日志，action为管理员执行的操作，调用链必须以Record()结尾，写入失败时返回错误
+// 示例：wlog.Audit(ctx, "user.delete").Field("operator", "admin").Field("uid", 1001).Record()
+func Audit(ctx context.Context, action string) AuditEntry {
+	return &auditEntry{
+		ctx:    ctx,
+		action: action,
+		fields: make(map[string]interface{}),
+	}
+}
+
+func (a *auditEntry) Field(key string, value interface{}) AuditEntry {
+	a.fields[key] = value
+	return a
+}
+
+func (a *auditEntry) Err(err error) AuditEntry {
+	a.err = err
+	return a
+}
+
+func (a *auditEntry) Record() error {
+	auditMu.RLock()
+	defer auditMu.RUnlock()
+	if auditor == nil {
+		return ErrAuditNotInitialized
+	}
+	record := &auditRecord{
+		Time:   time.Now().In(shanghaiLoc).Format(time.DateTime),
+		Action: a.action,
+	}
+	if a.ctx != nil {
+		record.TraceId = GetTraceId(a.ctx)
+	}
+	if len(a.fields) > 0 {
+		record.Fields = a.fields
+	}
+	if a.err != nil {
+		record.Error = a.err.Error()
+	}
+	return auditor.write(record)
+}
+
+type auditRecord struct {
+	Seq     uint64                 `json:"seq"`
+	Time    string                 `json:"time"`
+	Action  string                 `json:"action"`
+	TraceId string                 `json:"trace_id,omitempty"`
+	Fields  map[string]interface{} `json:"fields,omitempty"`
+	Error   string                 `json:"error,omitempty"`
+}
+
+// 每行格式为{...审计内容...,"hmac":"<hex>"}，hmac = HMAC-SHA256(key, 上一行的hmac + 本行审计内容)
+func (w *auditWriter) write(record *auditRecord) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	record.Seq = w.seq + 1
+	payload, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	mac := auditMAC(w.key, w.prevMAC, payload)
+	line := make([]byte, 0, len(payload)+len(mac)+12)
+	line = append(line, payload[:len(payload)-1]...)
+	line = append(line, `,"hmac":"`...)
+	line = append(line, mac...)
+	line = append(line, "\"}\n"...)
+	if _, err = w.file.Write(line); err != nil {
+		return err
+	}
+	// 审计日志数量不大，每条都落盘，避免进程崩溃时丢失
+	if err = w.file.Sync(); err != nil {
+		return err
+	}
+	w.seq = record.Seq
+	w.prevMAC = mac
+	return nil
+}
+
+func (w *auditWriter) close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.file.Close()
+}
+
+func auditMAC(key []byte, prevMAC string, payload []byte) string {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(prevMAC))
+	h.Write(payload)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+var auditMACPrefix = []byte(`,"hmac":"`)
+
+// 把一行审计日志拆分为审计内容和hmac，审计内容即计算hmac时使用的原始字节
+func splitAuditLine(line []byte) ([]byte, string, error) {
+	macLen := sha256.Size * 2
+	suffixLen := len(auditMACPrefix) + macLen + 2
+	if len(line) < suffixLen+2 || !bytes.HasSuffix(line, []byte(`"}`)) ||
+		!bytes.Equal(line[len(line)-suffixLen:len(line)-macLen-2], auditMACPrefix) {
+		return nil, "", errors.New("wlog: malformed audit line")
+	}
+	mac := string(line[len(line)-macLen-2 : len(line)-2])
+	payload := make([]byte, 0, len(line)-suffixLen+1)
+	payload = append(payload, line[:len(line)-suffixLen]...)
+	payload = append(payload, '}')
+	return payload, mac, nil
+}
+
+// lastAuditLine 返回最后一个完整的行，文件不以换行符结尾时同时返回末尾不完整部分的位置
+func lastAuditLine(f *os.File) ([]byte, *AuditPartialLineError, error) {
+	reader := bufio.NewReaderSize(f, 64*1024)
+	var last []byte
+	var offset int64
+	for {
+		line, err := reader.ReadBytes('\n')
+		if len(line) > 0 && line[len(line)-1] == '\n' {
+			last = line[:len(line)-1]
+			offset += int64(len(line))
+		} else if len(line) > 0 {
+			return last, &AuditPartialLineError{Path: f.Name(), Offset: offset, Size: len(line)}, nil
+		}
+		if err == io.EOF {
+			return last, nil, nil
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+}
+
+// repairPartialLine 把末尾不完整的内容保存到单独的文件中，再把审计文件截断到最后一个完整的行
+func repairPartialLine(path string, partial *AuditPartialLineError) error {
+	f, err := os.OpenFile(path, os.O_RDWR, 0)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	data := make([]byte, partial.Size)
+	if _, err = f.ReadAt(data, partial.Offset); err != nil {
+		return err
+	}
+	sidePath := fmt.Sprintf("%s.partial-%d", path, time.Now().Unix())
+	if err = os.WriteFile(sidePath, data, 0o600); err != nil {
+		return err
+	}
+	if err = f.Truncate(partial.Offset); err != nil {
+		return err
+	}
+	if err = f.Sync(); err != nil {
+		return err
+	}
+	Msg("audit file ended with an incomplete line, moved it aside").
+		Field("path", path).Field("partial_path", sidePath).Field("size", partial.Size).LevelWarn()
+	return nil
+}
+
+// AuditVerifyResult 审计文件的校验结果，LastSeq和LastMAC可以保存到外部，用于后续发现文件末尾被截断
+type AuditVerifyResult struct {
+	Lines   int
+	LastSeq uint64
+	LastMAC string
+}
+
+// AuditVerifyError 审计文件校验失败时返回的错误，Line为出问题的行号（从1开始）
+type AuditVerifyError struct {
+	Line   int
+	Reason string
+}
+
+func (e *AuditVerifyError) Error() string {
+	return fmt.Sprintf("wlog: audit verification failed at line %d: %s", e.Line, e.Reason)
+}
+
+// VerifyAudit 逐行校验审计日志的序号与哈希链，能够发现内容被修改、行被删除或调换顺序、文件开头或中间被截断
+// 文件末尾整行被删除时哈希链仍然完整，需要将结果中的LastSeq、LastMAC与外部保存的值比对才能发现
+func VerifyAudit(r io.Reader, key []byte) (*AuditVerifyResult, error) {
+	reader := bufio.NewReader(r)
+	result := &AuditVerifyResult{}
+	for {
+		line, err := reader.ReadBytes('\n')
+		if len(line) == 0 && err == io.EOF {
+			return result, nil
+		}
+		if err != nil && err != io.EOF {
+			return nil, err
+		}
+		lineNo := result.Lines + 1
+		if line[len(line)-1] != '\n' {
+			return nil, &AuditVerifyError{Line: lineNo, Reason: "incomplete last line, file may be truncated"}
+		}
+		payload, mac, splitErr := splitAuditLine(line[:len(line)-1])
+		if splitErr != nil {
+			return nil, &AuditVerifyError{Line: lineNo, Reason: splitErr.Error()}
+		}
+		var record auditRecord
+		if err = json.Unmarshal(payload, &record); err != nil {
+			return nil, &AuditVerifyError{Line: lineNo, Reason: "invalid json: " + err.Error()}
+		}
+		if record.Seq != result.LastSeq+1 {
+			reason := fmt.Sprintf("expected seq %d, got %d", result.LastSeq+1, record.Seq)
+			return nil, &AuditVerifyError{Line: lineNo, Reason: reason}
+		}
+		if !hmac.Equal([]byte(mac), []byte(auditMAC(key, result.LastMAC, payload))) {
+			return nil, &AuditVerifyError{Line: lineNo, Reason: "hmac mismatch, line was modified or chain is broken"}
+		}
+		result.Lines = lineNo
+		result.LastSeq = record.Seq
+		result.LastMAC = mac
+	}
+}
+
+// VerifyAuditFile 校验指定路径的审计文件，参见VerifyAudit
+func VerifyAuditFile(path string, key []byte) (*AuditVerifyResult, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return VerifyAudit(f, key)
+}
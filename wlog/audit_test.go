@@ -0,0 +1,187 @@
+package wlog
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var testAuditKey = []byte("audit-test-key")
+
+// writeAuditFile 写入n条审计日志，返回文件路径和各行内容（不含换行符）
+func writeAuditFile(t *testing.T, n int) (string, [][]byte) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "audit.log")
+	if err := InitAudit(AuditConfig{Path: path, Key: testAuditKey}); err != nil {
+		t.Fatal(err)
+	}
+	for i := 1; i <= n; i++ {
+		if err := Audit(context.Background(), "user.delete").Field("uid", i).Record(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := CloseAudit(); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return path, bytes.Split(bytes.TrimSuffix(data, []byte("\n")), []byte("\n"))
+}
+
+func joinLines(lines [][]byte) []byte {
+	return append(bytes.Join(lines, []byte("\n")), '\n')
+}
+
+func TestVerifyAuditValid(t *testing.T) {
+	path, lines := writeAuditFile(t, 5)
+	result, err := VerifyAuditFile(path, testAuditKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Lines != 5 || result.LastSeq != 5 {
+		t.Fatalf("got %+v", result)
+	}
+	if _, mac, _ := splitAuditLine(lines[4]); result.LastMAC != mac {
+		t.Fatalf("last mac %s, want %s", result.LastMAC, mac)
+	}
+}
+
+func TestVerifyAuditDetectsTampering(t *testing.T) {
+	_, lines := writeAuditFile(t, 5)
+	cases := []struct {
+		name     string
+		tamper   func(lines [][]byte) []byte
+		wantLine int
+	}{
+		{"edited field", func(lines [][]byte) []byte {
+			lines[2] = bytes.Replace(lines[2], []byte(`"uid":3`), []byte(`"uid":4`), 1)
+			return joinLines(lines)
+		}, 3},
+		{"reordered lines", func(lines [][]byte) []byte {
+			lines[1], lines[2] = lines[2], lines[1]
+			return joinLines(lines)
+		}, 2},
+		{"deleted middle line", func(lines [][]byte) []byte {
+			return joinLines(append(lines[:2:2], lines[3:]...))
+		}, 3},
+		{"truncated head", func(lines [][]byte) []byte {
+			return joinLines(lines[1:])
+		}, 1},
+		{"truncated mid-line", func(lines [][]byte) []byte {
+			data := joinLines(lines)
+			return data[:len(data)-10]
+		}, 5},
+		{"forged hmac", func(lines [][]byte) []byte {
+			payload, _, _ := splitAuditLine(lines[3])
+			forged := auditMAC([]byte("other-key"), "", payload)
+			lines[3] = append(payload[:len(payload)-1], []byte(`,"hmac":"`+forged+`"}`)...)
+			return joinLines(lines)
+		}, 4},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			copied := make([][]byte, len(lines))
+			for i, line := range lines {
+				copied[i] = append([]byte(nil), line...)
+			}
+			_, err := VerifyAudit(bytes.NewReader(c.tamper(copied)), testAuditKey)
+			var verifyErr *AuditVerifyError
+			if !errors.As(err, &verifyErr) {
+				t.Fatalf("expected AuditVerifyError, got %v", err)
+			}
+			if verifyErr.Line != c.wantLine {
+				t.Fatalf("failed at line %d (%s), want line %d", verifyErr.Line, verifyErr.Reason, c.wantLine)
+			}
+		})
+	}
+}
+
+// 末尾整行被删除时哈希链仍然完整，只能通过与外部保存的LastSeq比对发现
+func TestVerifyAuditTruncatedTail(t *testing.T) {
+	_, lines := writeAuditFile(t, 5)
+	result, err := VerifyAudit(bytes.NewReader(joinLines(lines[:4])), testAuditKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.LastSeq != 4 {
+		t.Fatalf("last seq %d, want 4", result.LastSeq)
+	}
+}
+
+func TestVerifyAuditWrongKey(t *testing.T) {
+	path, _ := writeAuditFile(t, 2)
+	_, err := VerifyAuditFile(path, []byte("wrong"))
+	var verifyErr *AuditVerifyError
+	if !errors.As(err, &verifyErr) || verifyErr.Line != 1 {
+		t.Fatalf("expected failure at line 1, got %v", err)
+	}
+}
+
+func TestInitAuditContinuesChain(t *testing.T) {
+	path, _ := writeAuditFile(t, 3)
+	if err := InitAudit(AuditConfig{Path: path, Key: testAuditKey}); err != nil {
+		t.Fatal(err)
+	}
+	if err := Audit(context.Background(), "user.create").Record(); err != nil {
+		t.Fatal(err)
+	}
+	_ = CloseAudit()
+	result, err := VerifyAuditFile(path, testAuditKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.LastSeq != 4 {
+		t.Fatalf("last seq %d, want 4", result.LastSeq)
+	}
+}
+
+func TestInitAuditPartialLine(t *testing.T) {
+	path, lines := writeAuditFile(t, 3)
+	partial := []byte(`{"seq":4,"time":"2024-01-01 00:00:00","act`)
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _ = f.Write(partial)
+	_ = f.Close()
+
+	err = InitAudit(AuditConfig{Path: path, Key: testAuditKey})
+	var partialErr *AuditPartialLineError
+	if !errors.Is(err, ErrAuditPartialLine) || !errors.As(err, &partialErr) {
+		t.Fatalf("expected ErrAuditPartialLine, got %v", err)
+	}
+	if want := int64(len(joinLines(lines))); partialErr.Offset != want || partialErr.Size != len(partial) {
+		t.Fatalf("got offset %d size %d, want offset %d size %d", partialErr.Offset, partialErr.Size, want, len(partial))
+	}
+
+	if err = InitAudit(AuditConfig{Path: path, Key: testAuditKey, RepairPartialLine: true}); err != nil {
+		t.Fatal(err)
+	}
+	if err = Audit(context.Background(), "user.create").Record(); err != nil {
+		t.Fatal(err)
+	}
+	_ = CloseAudit()
+	result, err := VerifyAuditFile(path, testAuditKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.LastSeq != 4 {
+		t.Fatalf("last seq %d, want 4", result.LastSeq)
+	}
+	sideFiles, _ := filepath.Glob(path + ".partial-*")
+	if len(sideFiles) != 1 {
+		t.Fatalf("expected one partial file, got %v", sideFiles)
+	}
+	if data, _ := os.ReadFile(sideFiles[0]); !bytes.Equal(data, partial) {
+		t.Fatalf("partial file content %q", data)
+	}
+	if data, _ := os.ReadFile(path); strings.Contains(string(data), `"act`+"\n") {
+		t.Fatal("partial content should be removed from audit file")
+	}
+}
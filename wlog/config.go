@@ -1,8 +1,11 @@
 package wlog
 
 import (
+	"errors"
+	"fmt"
 	"log"
 	"os"
+	"sync/atomic"
 	"time"
 
 	"go.uber.org/zap"
@@ -10,7 +13,9 @@ import (
 )
 
 var (
-	logger      *zap.Logger
+	baseLogger  *zap.Logger                // 仅输出到控制台（标准输出）的logger
+	baseCloser  func()                     // 关闭baseLogger自行打开的输出，替换baseLogger后调用
+	logger      atomic.Pointer[zap.Logger] // 实际使用的logger，挂载了Sink后会同时写入额外的输出目标
 	shanghaiLoc *time.Location
 )
 
@@ -21,11 +26,94 @@ func init() {
 		shanghaiLoc = time.UTC // 如果加载时区出错，则使用UTC时间
 		err = nil
 	}
-	zapConfig := loadZapConfig()
-	logger, err = zapConfig.Build()
+	// 可以通过LOG_ENCODING环境变量直接选择json、console或logfmt格式，不设置时保持原有行为
+	// 环境变量的值无效时输出告警并使用默认格式，不能因为配置错误导致引入wlog的程序无法启动
+	baseLogger, baseCloser, err = buildLogger(Config{Encoding: os.Getenv("LOG_ENCODING")})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "wlog: ignore LOG_ENCODING, err: %v\n", err)
+		baseLogger, baseCloser, err = buildLogger(Config{})
+	}
 	if err != nil {
 		log.Fatalf("failed to initialize logger, err: %v", err)
 	}
+	logger.Store(baseLogger)
+}
+
+// Config 控制台日志的输出配置
+type Config struct {
+	// 日志格式，可选json、console、logfmt，为空时开发环境使用zap自带的彩色控制台格式，其余环境使用json
+	Encoding string
+	// Encoding为console时的列顺序、字段重命名和着色配置
+	Console ConsoleConfig
+}
+
+// Configure 按配置重新创建控制台logger，已挂载的Sink保持不变，一般在main函数开头调用一次
+func Configure(cfg Config) error {
+	newLogger, closer, err := buildLogger(cfg)
+	if err != nil {
+		return err
+	}
+	sinkMu.Lock()
+	defer sinkMu.Unlock()
+	oldLogger, oldCloser := baseLogger, baseCloser
+	baseLogger, baseCloser = newLogger, closer
+	rebuildLogger()
+	if oldCloser != nil {
+		_ = oldLogger.Sync()
+		oldCloser()
+	}
+	return nil
+}
+
+// buildLogger 返回的closer用于关闭logfmt、console格式自行打开的输出，其余格式为nil
+func buildLogger(cfg Config) (*zap.Logger, func(), error) {
+	zapConfig := loadZapConfig()
+	opts := []zap.Option{zap.WithFatalHook(fatalHook{})}
+	var encoder zapcore.Encoder
+	switch cfg.Encoding {
+	case "":
+		l, err := zapConfig.Build(opts...)
+		return l, nil, err
+	case "json":
+		zapConfig.Encoding = "json"
+		zapConfig.EncoderConfig = structuredEncoderConfig()
+		l, err := zapConfig.Build(opts...)
+		return l, nil, err
+	case "logfmt":
+		encoder = newLogfmtEncoder(structuredEncoderConfig())
+	case "console":
+		if !consoleColumnsValid(cfg.Console.Columns) {
+			return nil, nil, errors.New("wlog: console column name is empty")
+		}
+		encoder = newConsoleEncoder(cfg.Console)
+	default:
+		return nil, nil, fmt.Errorf("wlog: unsupported encoding %q", cfg.Encoding)
+	}
+	output, closer, err := zap.Open(zapConfig.OutputPaths...)
+	if err != nil {
+		return nil, nil, err
+	}
+	// 复用zapConfig.Build设置的调用位置、错误输出等选项，只替换其中的编码器
+	// Build内置的采样会随原有core一起被替换，这里按同样的参数重新包装；输出已经自行打开，Build时不再重复打开
+	sampling := zapConfig.Sampling
+	zapConfig.OutputPaths = nil
+	opts = append(opts, zap.WrapCore(func(zapcore.Core) zapcore.Core {
+		core := zapcore.NewCore(encoder, output, zapConfig.Level)
+		if sampling != nil {
+			var samplerOpts []zapcore.SamplerOption
+			if sampling.Hook != nil {
+				samplerOpts = append(samplerOpts, zapcore.SamplerHook(sampling.Hook))
+			}
+			core = zapcore.NewSamplerWithOptions(core, time.Second, sampling.Initial, sampling.Thereafter, samplerOpts...)
+		}
+		return core
+	}))
+	l, err := zapConfig.Build(opts...)
+	if err != nil {
+		closer()
+		return nil, nil, err
+	}
+	return l, closer, nil
 }
 
 func isDevEnv() bool {
@@ -43,20 +131,30 @@ func loadZapConfig() zap.Config {
 	if isDevEnv() {
 		zapConfig = zap.NewDevelopmentConfig()
 		zapConfig.EncoderConfig.EncodeLevel = zapcore.CapitalColorLevelEncoder
+		zapConfig.EncoderConfig.EncodeTime = customTimeEncoder
+		zapConfig.EncoderConfig.EncodeCaller = zapcore.ShortCallerEncoder
 		zapConfig.OutputPaths = []string{"stdout"}
 	} else {
 		zapConfig = zap.NewProductionConfig()
-		zapConfig.EncoderConfig.TimeKey = "time"
-		zapConfig.EncoderConfig.MessageKey = "message"
-		zapConfig.EncoderConfig.CallerKey = "line"
-		zapConfig.EncoderConfig.EncodeLevel = zapcore.CapitalLevelEncoder
+		zapConfig.EncoderConfig = structuredEncoderConfig()
 	}
-	zapConfig.EncoderConfig.EncodeTime = customTimeEncoder
-	zapConfig.EncoderConfig.EncodeCaller = zapcore.ShortCallerEncoder
 	zapConfig.DisableStacktrace = true
 	return zapConfig
 }
 
+// 结构化日志使用的编码配置，控制台的生产环境输出与各类Sink保持一致的字段名
+func structuredEncoderConfig() zapcore.EncoderConfig {
+	encoderConfig := zap.NewProductionEncoderConfig()
+	encoderConfig.TimeKey = "time"
+	encoderConfig.MessageKey = "message"
+	encoderConfig.CallerKey = "line"
+	encoderConfig.StacktraceKey = ""
+	encoderConfig.EncodeLevel = zapcore.CapitalLevelEncoder
+	encoderConfig.EncodeTime = customTimeEncoder
+	encoderConfig.EncodeCaller = zapcore.ShortCallerEncoder
+	return encoderConfig
+}
+
 func customTimeEncoder(t time.Time, enc zapcore.PrimitiveArrayEncoder) {
 	t = t.In(shanghaiLoc)
 	enc.AppendString(t.Format(time.DateTime))
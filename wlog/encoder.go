@@ -0,0 +1,295 @@
+package wlog
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"go.uber.org/zap/buffer"
+	"go.uber.org/zap/zapcore"
+)
+
+var bufferPool = buffer.NewPool()
+
+// ConsoleConfig 控制台格式的输出配置，固定列之间使用制表符分隔，其余字段以key=value的形式追加在末尾
+type ConsoleConfig struct {
+	// 固定列及其顺序，可选time、level、line、message，以及trace_id、caller等字段名，
+	// 默认为time、level、line、trace_id、caller、message，某条日志缺少对应字段时该列输出"-"
+	Columns []string
+	// 末尾字段的字段名重命名，如{"trace_id": "tid"}
+	KeyRename map[string]string
+	// 是否对日志等级着色，为nil时仅在开发环境着色
+	Color *bool
+}
+
+var defaultConsoleColumns = []string{"time", "level", "line", "trace_id", "caller", "message"}
+
+type field struct {
+	key   string
+	value interface{}
+}
+
+// fieldCollector 按添加顺序收集日志字段，供logfmt和console两种编码器共用
+type fieldCollector struct {
+	namespace string
+	fields    []field
+}
+
+func (f *fieldCollector) add(key string, value interface{}) {
+	f.fields = append(f.fields, field{key: f.namespace + key, value: value})
+}
+
+func (f *fieldCollector) clone() *fieldCollector {
+	fields := make([]field, len(f.fields), len(f.fields)+8)
+	copy(fields, f.fields)
+	return &fieldCollector{namespace: f.namespace, fields: fields}
+}
+
+func (f *fieldCollector) AddArray(key string, marshaler zapcore.ArrayMarshaler) error {
+	enc := zapcore.NewMapObjectEncoder()
+	err := enc.AddArray(key, marshaler)
+	f.add(key, enc.Fields[key])
+	return err
+}
+
+func (f *fieldCollector) AddObject(key string, marshaler zapcore.ObjectMarshaler) error {
+	enc := zapcore.NewMapObjectEncoder()
+	err := marshaler.MarshalLogObject(enc)
+	f.add(key, enc.Fields)
+	return err
+}
+
+func (f *fieldCollector) AddBinary(key string, value []byte) {
+	f.add(key, base64.StdEncoding.EncodeToString(value))
+}
+
+func (f *fieldCollector) AddByteString(key string, value []byte) { f.add(key, string(value)) }
+func (f *fieldCollector) AddBool(key string, value bool)         { f.add(key, value) }
+func (f *fieldCollector) AddComplex128(key string, value complex128) {
+	f.add(key, strconv.FormatComplex(value, 'g', -1, 128))
+}
+func (f *fieldCollector) AddComplex64(key string, value complex64) {
+	f.add(key, strconv.FormatComplex(complex128(value), 'g', -1, 64))
+}
+func (f *fieldCollector) AddDuration(key string, value time.Duration) { f.add(key, value.String()) }
+func (f *fieldCollector) AddFloat64(key string, value float64)        { f.add(key, value) }
+func (f *fieldCollector) AddFloat32(key string, value float32)        { f.add(key, value) }
+func (f *fieldCollector) AddInt(key string, value int)                { f.add(key, value) }
+func (f *fieldCollector) AddInt64(key string, value int64)            { f.add(key, value) }
+func (f *fieldCollector) AddInt32(key string, value int32)            { f.add(key, value) }
+func (f *fieldCollector) AddInt16(key string, value int16)            { f.add(key, value) }
+func (f *fieldCollector) AddInt8(key string, value int8)              { f.add(key, value) }
+func (f *fieldCollector) AddString(key, value string)                 { f.add(key, value) }
+func (f *fieldCollector) AddTime(key string, value time.Time) {
+	f.add(key, value.In(shanghaiLoc).Format(time.DateTime))
+}
+func (f *fieldCollector) AddUint(key string, value uint)       { f.add(key, value) }
+func (f *fieldCollector) AddUint64(key string, value uint64)   { f.add(key, value) }
+func (f *fieldCollector) AddUint32(key string, value uint32)   { f.add(key, value) }
+func (f *fieldCollector) AddUint16(key string, value uint16)   { f.add(key, value) }
+func (f *fieldCollector) AddUint8(key string, value uint8)     { f.add(key, value) }
+func (f *fieldCollector) AddUintptr(key string, value uintptr) { f.add(key, value) }
+
+func (f *fieldCollector) AddReflected(key string, value interface{}) error {
+	f.add(key, value)
+	return nil
+}
+
+// OpenNamespace 之后添加的字段名统一加上"namespace."前缀
+func (f *fieldCollector) OpenNamespace(key string) {
+	f.namespace += key + "."
+}
+
+// 把字段值格式化为字符串，字符串和数字直接输出，其余类型序列化为JSON
+func formatValue(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case bool:
+		return strconv.FormatBool(v)
+	case float64:
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	case float32:
+		return strconv.FormatFloat(float64(v), 'g', -1, 32)
+	case int, int64, int32, int16, int8, uint, uint64, uint32, uint16, uint8, uintptr:
+		return fmt.Sprint(v)
+	case error:
+		return v.Error()
+	default:
+		data, err := json.Marshal(v)
+		if err != nil {
+			return fmt.Sprintf("%+v", v)
+		}
+		return string(data)
+	}
+}
+
+// logfmt中值包含空格、等号、引号或为空时需要加引号
+func appendLogfmtValue(buf *buffer.Buffer, value string) {
+	needQuote := value == "" || !utf8.ValidString(value)
+	for _, r := range value {
+		if r <= ' ' || r == '=' || r == '"' || r == utf8.RuneError {
+			needQuote = true
+			break
+		}
+	}
+	if needQuote {
+		buf.AppendString(strconv.Quote(value))
+	} else {
+		buf.AppendString(value)
+	}
+}
+
+// logfmtEncoder 输出形如time="2024-04-22 15:47:03" level=WARN line=prac/main.go:17 message=hello name=zhangsan的日志
+type logfmtEncoder struct {
+	*fieldCollector
+	cfg zapcore.EncoderConfig
+}
+
+func newLogfmtEncoder(cfg zapcore.EncoderConfig) zapcore.Encoder {
+	return &logfmtEncoder{fieldCollector: &fieldCollector{}, cfg: cfg}
+}
+
+func (e *logfmtEncoder) Clone() zapcore.Encoder {
+	return &logfmtEncoder{fieldCollector: e.fieldCollector.clone(), cfg: e.cfg}
+}
+
+func (e *logfmtEncoder) EncodeEntry(ent zapcore.Entry, fields []zapcore.Field) (*buffer.Buffer, error) {
+	buf := bufferPool.Get()
+	appendPair := func(key, value string) {
+		if buf.Len() > 0 {
+			buf.AppendByte(' ')
+		}
+		buf.AppendString(key)
+		buf.AppendByte('=')
+		appendLogfmtValue(buf, value)
+	}
+	if e.cfg.TimeKey != "" {
+		appendPair(e.cfg.TimeKey, ent.Time.In(shanghaiLoc).Format(time.DateTime))
+	}
+	if e.cfg.LevelKey != "" {
+		appendPair(e.cfg.LevelKey, ent.Level.CapitalString())
+	}
+	if e.cfg.CallerKey != "" && ent.Caller.Defined {
+		appendPair(e.cfg.CallerKey, ent.Caller.TrimmedPath())
+	}
+	if e.cfg.MessageKey != "" {
+		appendPair(e.cfg.MessageKey, ent.Message)
+	}
+	collector := e.fieldCollector.clone()
+	for _, f := range fields {
+		f.AddTo(collector)
+	}
+	for _, f := range collector.fields {
+		appendPair(f.key, formatValue(f.value))
+	}
+	buf.AppendString(zapcore.DefaultLineEnding)
+	return buf, nil
+}
+
+// consoleEncoder 人工阅读用的控制台格式，固定列的位置不随字段变化，便于对齐查看trace_id和caller
+type consoleEncoder struct {
+	*fieldCollector
+	cfg   ConsoleConfig
+	color bool
+}
+
+func newConsoleEncoder(cfg ConsoleConfig) zapcore.Encoder {
+	if len(cfg.Columns) == 0 {
+		cfg.Columns = defaultConsoleColumns
+	}
+	color := isDevEnv()
+	if cfg.Color != nil {
+		color = *cfg.Color
+	}
+	return &consoleEncoder{fieldCollector: &fieldCollector{}, cfg: cfg, color: color}
+}
+
+func (e *consoleEncoder) Clone() zapcore.Encoder {
+	return &consoleEncoder{fieldCollector: e.fieldCollector.clone(), cfg: e.cfg, color: e.color}
+}
+
+func (e *consoleEncoder) EncodeEntry(ent zapcore.Entry, fields []zapcore.Field) (*buffer.Buffer, error) {
+	collector := e.fieldCollector.clone()
+	for _, f := range fields {
+		f.AddTo(collector)
+	}
+	used := make(map[int]bool)
+	buf := bufferPool.Get()
+	for i, column := range e.cfg.Columns {
+		if i > 0 {
+			buf.AppendByte('\t')
+		}
+		switch column {
+		case "time":
+			buf.AppendString(ent.Time.In(shanghaiLoc).Format(time.DateTime))
+		case "level":
+			e.appendLevel(buf, ent.Level)
+		case "line":
+			if ent.Caller.Defined {
+				buf.AppendString(ent.Caller.TrimmedPath())
+			} else {
+				buf.AppendByte('-')
+			}
+		case "message":
+			buf.AppendString(ent.Message)
+		default:
+			value := "-"
+			for j, f := range collector.fields {
+				if f.key == column && !used[j] {
+					value = formatValue(f.value)
+					used[j] = true
+					break
+				}
+			}
+			buf.AppendString(value)
+		}
+	}
+	for j, f := range collector.fields {
+		if used[j] {
+			continue
+		}
+		key := f.key
+		if renamed, ok := e.cfg.KeyRename[key]; ok {
+			key = renamed
+		}
+		buf.AppendByte(' ')
+		buf.AppendString(key)
+		buf.AppendByte('=')
+		appendLogfmtValue(buf, formatValue(f.value))
+	}
+	buf.AppendString(zapcore.DefaultLineEnding)
+	return buf, nil
+}
+
+// 等级统一补齐为5个字符宽度，保证后续列对齐
+func (e *consoleEncoder) appendLevel(buf *buffer.Buffer, level zapcore.Level) {
+	text := fmt.Sprintf("%-5s", level.CapitalString())
+	if !e.color {
+		buf.AppendString(text)
+		return
+	}
+	colorCode := 31 // 红色
+	switch level {
+	case zapcore.DebugLevel:
+		colorCode = 35 // 紫色
+	case zapcore.InfoLevel:
+		colorCode = 34 // 蓝色
+	case zapcore.WarnLevel:
+		colorCode = 33 // 黄色
+	}
+	buf.AppendString("\x1b[" + strconv.Itoa(colorCode) + "m" + text + "\x1b[0m")
+}
+
+func consoleColumnsValid(columns []string) bool {
+	for _, column := range columns {
+		if strings.TrimSpace(column) == "" {
+			return false
+		}
+	}
+	return true
+}
@@ -2,6 +2,7 @@ package wlog
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"path"
 	"runtime"
@@ -48,7 +49,7 @@ type loggerEntry struct {
 
 func Msg(message string) LoggerEntry {
 	return &loggerEntry{
-		logger:  logger,
+		logger:  logger.Load(),
 		message: message,
 		// 默认跳过2层调用者，write占1层，日志等级方法（如Error()）占1层
 		callerSkip: defaultCallerSkip,
@@ -76,8 +77,20 @@ func (l *loggerEntry) Field(key string, value interface{}) LoggerEntry {
 	return l
 }
 
+// CodedError 携带业务错误码和HTTP状态码的错误，wresp.NewErrorCode创建的错误实现了该接口
+// 其他包的错误类型只要实现这两个方法，Err()也会将其输出为error_code和http_status字段
+type CodedError interface {
+	error
+	ErrorCode() int
+	HTTPStatus() int
+}
+
 func (l *loggerEntry) Err(err error) LoggerEntry {
 	l.logger = l.logger.With(zap.Error(err))
+	var coded CodedError
+	if errors.As(err, &coded) {
+		l.logger = l.logger.With(zap.Int("error_code", coded.ErrorCode()), zap.Int("http_status", coded.HTTPStatus()))
+	}
 	return l
 }
 
@@ -0,0 +1,71 @@
+package wlog
+
+import (
+	"os"
+	"sync"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+var (
+	exitMu      sync.Mutex
+	exitHooks   []func()
+	exitTimeout = 5 * time.Second
+)
+
+// OnExit 注册进程因Fatal日志退出前执行的清理函数，如关闭数据库连接池、从注册中心下线等
+// 清理函数按注册的逆序执行（与defer一致），所有清理函数共享SetExitTimeout设置的超时时间
+func OnExit(hook func()) {
+	exitMu.Lock()
+	defer exitMu.Unlock()
+	exitHooks = append(exitHooks, hook)
+}
+
+// SetExitTimeout 设置执行清理函数的总超时时间，超时后不再等待，直接退出进程，默认5秒
+func SetExitTimeout(timeout time.Duration) {
+	exitMu.Lock()
+	defer exitMu.Unlock()
+	exitTimeout = timeout
+}
+
+// fatalHook 替换zap在Fatal日志写入后直接调用os.Exit的默认行为
+type fatalHook struct{}
+
+func (fatalHook) OnWrite(*zapcore.CheckedEntry, []zapcore.Field) {
+	// 先刷出已缓冲的日志，保证Fatal这条日志本身不会因为清理函数卡住而丢失
+	_ = Sync()
+	runExitHooks()
+	_ = Close()
+	_ = CloseAudit()
+	os.Exit(1)
+}
+
+func runExitHooks() {
+	exitMu.Lock()
+	hooks := make([]func(), len(exitHooks))
+	copy(hooks, exitHooks)
+	timeout := exitTimeout
+	exitMu.Unlock()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := len(hooks) - 1; i >= 0; i-- {
+			runExitHook(hooks[i])
+		}
+	}()
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case <-done:
+	case <-timer.C:
+	}
+}
+
+// 单个清理函数panic时不影响其余清理函数的执行
+func runExitHook(hook func()) {
+	defer func() {
+		_ = recover()
+	}()
+	hook()
+}
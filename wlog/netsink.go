@@ -0,0 +1,237 @@
+package wlog
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// NetSinkConfig 网络日志输出目标的配置，未设置的字段使用默认值
+type NetSinkConfig struct {
+	Network      string        // 网络类型，支持tcp、udp、unix、unixgram
+	Address      string        // 目标地址，如127.0.0.1:24224、/dev/log
+	Level        zapcore.Level // 最低输出等级，默认Info
+	BufferSize   int           // 内存中缓冲的日志条数，默认1024
+	DialTimeout  time.Duration // 建立连接的超时时间，默认3秒
+	WriteTimeout time.Duration // 单条日志的写超时时间，默认3秒
+	MinBackoff   time.Duration // 首次重连等待时间，默认500毫秒
+	MaxBackoff   time.Duration // 最大重连等待时间，默认30秒
+	SpillPath    string        // 断连期间暂存日志的磁盘文件，为空表示不落盘，缓冲区满后直接丢弃
+	SpillAfter   time.Duration // 断连超过该时长后缓冲中的日志开始落盘，默认10秒
+	FlushTimeout time.Duration // Sync与Close的最长等待时间，默认5秒
+}
+
+// SyslogConfig RFC 5424格式syslog输出目标的配置
+type SyslogConfig struct {
+	NetSinkConfig
+	Facility int    // syslog设施值，默认1（user-level）
+	Hostname string // 默认使用os.Hostname()
+	AppName  string // 默认使用可执行文件名
+}
+
+// DropCounter 由NewJSONSink和NewSyslogSink返回的Sink实现，可以通过类型断言取得丢弃的日志条数用于监控告警
+//
+//	if counter, ok := sink.(wlog.DropCounter); ok {
+//		dropped := counter.Dropped()
+//	}
+type DropCounter interface {
+	// Dropped 返回因缓冲区已满且无法落盘而丢弃的日志条数
+	Dropped() int64
+}
+
+type netSink struct {
+	core   zapcore.Core
+	writer *netWriter
+}
+
+func (s *netSink) Core() zapcore.Core {
+	return s.core
+}
+
+func (s *netSink) Close() error {
+	return s.writer.Close()
+}
+
+func (s *netSink) Dropped() int64 {
+	return s.writer.dropped.Load()
+}
+
+// NewJSONSink 创建按行分隔的JSON日志输出目标，适用于Fluent Bit、Logstash等采集端的TCP输入
+func NewJSONSink(cfg NetSinkConfig) (Sink, error) {
+	if err := cfg.normalize(); err != nil {
+		return nil, err
+	}
+	writer := newNetWriter(cfg)
+	core := zapcore.NewCore(zapcore.NewJSONEncoder(structuredEncoderConfig()), writer, cfg.Level)
+	return &netSink{core: core, writer: writer}, nil
+}
+
+// NewSyslogSink 创建RFC 5424格式的syslog输出目标，日志正文为JSON格式
+// TCP等流式连接使用RFC 6587的octet-counting分帧，UDP等数据报连接每条日志对应一个数据报
+func NewSyslogSink(cfg SyslogConfig) (Sink, error) {
+	if err := cfg.normalize(); err != nil {
+		return nil, err
+	}
+	if cfg.Facility < 0 || cfg.Facility > 23 {
+		return nil, fmt.Errorf("wlog: invalid syslog facility %d", cfg.Facility)
+	}
+	if cfg.Facility == 0 {
+		cfg.Facility = 1
+	}
+	if cfg.Hostname == "" {
+		cfg.Hostname, _ = os.Hostname()
+	}
+	if cfg.AppName == "" {
+		cfg.AppName = filepath.Base(os.Args[0])
+	}
+	writer := newNetWriter(cfg.NetSinkConfig)
+	encoderConfig := structuredEncoderConfig()
+	// 时间和等级已经体现在syslog头部中，正文中不再重复
+	encoderConfig.TimeKey = ""
+	encoderConfig.LevelKey = ""
+	encoderConfig.LineEnding = ""
+	core := &syslogCore{
+		LevelEnabler: cfg.Level,
+		enc:          zapcore.NewJSONEncoder(encoderConfig),
+		out:          writer,
+		facility:     cfg.Facility,
+		hostname:     syslogHeaderField(cfg.Hostname, 255),
+		appName:      syslogHeaderField(cfg.AppName, 48),
+		procID:       strconv.Itoa(os.Getpid()),
+		stream:       cfg.Network == "tcp" || cfg.Network == "unix",
+	}
+	return &netSink{core: core, writer: writer}, nil
+}
+
+func (cfg *NetSinkConfig) normalize() error {
+	switch cfg.Network {
+	case "tcp", "udp", "unix", "unixgram":
+	default:
+		return fmt.Errorf("wlog: unsupported network %q", cfg.Network)
+	}
+	if cfg.Address == "" {
+		return errors.New("wlog: sink address is empty")
+	}
+	if cfg.BufferSize <= 0 {
+		cfg.BufferSize = 1024
+	}
+	if cfg.DialTimeout <= 0 {
+		cfg.DialTimeout = 3 * time.Second
+	}
+	if cfg.WriteTimeout <= 0 {
+		cfg.WriteTimeout = 3 * time.Second
+	}
+	if cfg.MinBackoff <= 0 {
+		cfg.MinBackoff = 500 * time.Millisecond
+	}
+	if cfg.MaxBackoff < cfg.MinBackoff {
+		cfg.MaxBackoff = 30 * time.Second
+	}
+	if cfg.SpillAfter <= 0 {
+		cfg.SpillAfter = 10 * time.Second
+	}
+	if cfg.FlushTimeout <= 0 {
+		cfg.FlushTimeout = 5 * time.Second
+	}
+	return nil
+}
+
+type syslogCore struct {
+	zapcore.LevelEnabler
+	enc      zapcore.Encoder
+	out      *netWriter
+	facility int
+	hostname string
+	appName  string
+	procID   string
+	stream   bool
+}
+
+func (c *syslogCore) With(fields []zapcore.Field) zapcore.Core {
+	clone := *c
+	clone.enc = c.enc.Clone()
+	for _, field := range fields {
+		field.AddTo(clone.enc)
+	}
+	return &clone
+}
+
+func (c *syslogCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+// Write 按照<PRI>1 TIMESTAMP HOSTNAME APP-NAME PROCID MSGID STRUCTURED-DATA MSG的格式输出
+func (c *syslogCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	buf, err := c.enc.EncodeEntry(ent, fields)
+	if err != nil {
+		return err
+	}
+	defer buf.Free()
+	msgID := "-"
+	if ent.LoggerName != "" {
+		msgID = syslogHeaderField(ent.LoggerName, 32)
+	}
+	msg := fmt.Sprintf("<%d>1 %s %s %s %s %s - %s",
+		c.facility*8+syslogSeverity(ent.Level),
+		ent.Time.Format("2006-01-02T15:04:05.000000Z07:00"),
+		c.hostname, c.appName, c.procID, msgID, buf.String())
+	if c.stream {
+		msg = strconv.Itoa(len(msg)) + " " + msg
+	}
+	if _, err = c.out.Write([]byte(msg)); err != nil {
+		return err
+	}
+	if ent.Level > zapcore.ErrorLevel {
+		_ = c.Sync()
+	}
+	return nil
+}
+
+func (c *syslogCore) Sync() error {
+	return c.out.Sync()
+}
+
+func syslogSeverity(level zapcore.Level) int {
+	switch level {
+	case zapcore.DebugLevel:
+		return 7
+	case zapcore.InfoLevel:
+		return 6
+	case zapcore.WarnLevel:
+		return 4
+	case zapcore.ErrorLevel:
+		return 3
+	case zapcore.DPanicLevel:
+		return 2
+	case zapcore.PanicLevel:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// RFC 5424要求头部字段为不含空格的可打印ASCII字符，并限制了最大长度，为空时使用"-"
+func syslogHeaderField(s string, maxLen int) string {
+	s = strings.Map(func(r rune) rune {
+		if r < 33 || r > 126 {
+			return '_'
+		}
+		return r
+	}, s)
+	if len(s) > maxLen {
+		s = s[:maxLen]
+	}
+	if s == "" {
+		return "-"
+	}
+	return s
+}
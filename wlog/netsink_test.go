@@ -0,0 +1,249 @@
+package wlog
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+func testNetConfig(addr string) NetSinkConfig {
+	cfg := NetSinkConfig{
+		Network:      "tcp",
+		Address:      addr,
+		MinBackoff:   10 * time.Millisecond,
+		MaxBackoff:   50 * time.Millisecond,
+		FlushTimeout: 2 * time.Second,
+	}
+	_ = cfg.normalize()
+	return cfg
+}
+
+// freeAddr 返回一个当前没有监听的本地地址
+func freeAddr(t *testing.T) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := ln.Addr().String()
+	_ = ln.Close()
+	return addr
+}
+
+// readLines 接受一个连接并按行读取，直到读满n行
+func readLines(t *testing.T, ln net.Listener, n int) []string {
+	t.Helper()
+	_ = ln.(*net.TCPListener).SetDeadline(time.Now().Add(5 * time.Second))
+	conn, err := ln.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	reader := bufio.NewReader(conn)
+	lines := make([]string, 0, n)
+	for len(lines) < n {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			t.Fatalf("read after %d lines: %v", len(lines), err)
+		}
+		lines = append(lines, strings.TrimSuffix(line, "\n"))
+	}
+	return lines
+}
+
+func TestSyslogSinkOctetCounting(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	sink, err := NewSyslogSink(SyslogConfig{NetSinkConfig: testNetConfig(ln.Addr().String()), Hostname: "host", AppName: "app"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	logger := zap.New(sink.Core())
+	logger.Info("first")
+	logger.Warn("second\nwith newline")
+	if err = sink.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	conn, err := ln.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	reader := bufio.NewReader(conn)
+	wantPrefix := []string{"<14>1 ", "<12>1 "} // facility 1，等级分别为info(6)和warning(4)
+	wantMsg := []string{"first", `second\nwith newline`}
+	for i := range wantPrefix {
+		lengthField, err := reader.ReadString(' ')
+		if err != nil {
+			t.Fatalf("frame %d: read length: %v", i, err)
+		}
+		length, err := strconv.Atoi(strings.TrimSuffix(lengthField, " "))
+		if err != nil {
+			t.Fatalf("frame %d: invalid length %q", i, lengthField)
+		}
+		frame := make([]byte, length)
+		if _, err = io.ReadFull(reader, frame); err != nil {
+			t.Fatalf("frame %d: read body: %v", i, err)
+		}
+		if !strings.HasPrefix(string(frame), wantPrefix[i]) {
+			t.Errorf("frame %d: got %q, want prefix %q", i, frame, wantPrefix[i])
+		}
+		if !strings.Contains(string(frame), " host app ") || !strings.Contains(string(frame), wantMsg[i]) {
+			t.Errorf("frame %d: unexpected content %q", i, frame)
+		}
+	}
+}
+
+func TestNetWriterReconnect(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := ln.Addr().String()
+	w := newNetWriter(testNetConfig(addr))
+	defer w.Close()
+
+	_, _ = w.Write([]byte("before\n"))
+	if got := readLines(t, ln, 1); got[0] != "before" {
+		t.Fatalf("got %q", got[0])
+	}
+	_ = ln.Close()
+
+	ln, err = net.Listen("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	// 对端关闭后的第一次写入可能在本地成功而丢失，持续写入直到新的监听端收到消息
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		for i := 0; ; i++ {
+			select {
+			case <-done:
+				return
+			case <-time.After(10 * time.Millisecond):
+				_, _ = w.Write([]byte(fmt.Sprintf("after-%d\n", i)))
+			}
+		}
+	}()
+	got := readLines(t, ln, 3)
+	prev := -1
+	for _, line := range got {
+		n, err := strconv.Atoi(strings.TrimPrefix(line, "after-"))
+		if err != nil || n <= prev {
+			t.Fatalf("unexpected lines after reconnect: %q", got)
+		}
+		prev = n
+	}
+}
+
+func TestNetWriterSpillReplayOrder(t *testing.T) {
+	addr := freeAddr(t)
+	cfg := testNetConfig(addr)
+	cfg.BufferSize = 4
+	cfg.SpillAfter = time.Hour // 后台Goroutine一直等待重连，落盘只能由缓冲区溢出和Sync触发
+	cfg.SpillPath = filepath.Join(t.TempDir(), "spill")
+	w := newNetWriter(cfg)
+	defer w.Close()
+
+	var want []string
+	for i := 0; i < 20; i++ {
+		msg := fmt.Sprintf("m%02d", i)
+		want = append(want, msg)
+		_, _ = w.Write([]byte(msg + "\n"))
+	}
+	start := time.Now()
+	if err := w.Sync(); err != nil {
+		t.Fatalf("sync during outage: %v", err)
+	}
+	if time.Since(start) > time.Second {
+		t.Fatalf("sync took %v during outage", time.Since(start))
+	}
+
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	_, _ = w.Write([]byte("m20\n"))
+	want = append(want, "m20")
+	got := readLines(t, ln, len(want))
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Fatalf("replay order mismatch:\n got %q\nwant %q", got, want)
+	}
+}
+
+func TestNetWriterReplaysLeftoverReplayFile(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	cfg := testNetConfig(ln.Addr().String())
+	cfg.SpillPath = filepath.Join(t.TempDir(), "spill")
+	// 模拟补发过程中崩溃：.replay中的消息比落盘文件中的更早
+	writeRecords(t, cfg.SpillPath+".replay", "r0", "r1")
+	writeRecords(t, cfg.SpillPath, "s0")
+
+	w := newNetWriter(cfg)
+	defer w.Close()
+	_, _ = w.Write([]byte("new\n"))
+	got := readLines(t, ln, 4)
+	if want := "r0,r1,s0,new"; strings.Join(got, ",") != want {
+		t.Fatalf("got %q, want %s", got, want)
+	}
+	if _, err = os.Stat(cfg.SpillPath + ".replay"); !os.IsNotExist(err) {
+		t.Fatalf("replay file should be removed, stat err: %v", err)
+	}
+}
+
+func writeRecords(t *testing.T, path string, msgs ...string) {
+	t.Helper()
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	for _, msg := range msgs {
+		if err = writeSpillRecord(f, []byte(msg+"\n")); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestNetSinkDropped(t *testing.T) {
+	cfg := testNetConfig(freeAddr(t))
+	cfg.BufferSize = 2
+	sink, err := NewJSONSink(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sink.Close()
+	logger := zap.New(sink.Core())
+	for i := 0; i < 5; i++ {
+		logger.Info("msg")
+	}
+	counter, ok := sink.(DropCounter)
+	if !ok {
+		t.Fatal("net sink should implement DropCounter")
+	}
+	if got := counter.Dropped(); got != 3 {
+		t.Fatalf("dropped %d, want 3", got)
+	}
+}
@@ -0,0 +1,423 @@
+package wlog
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+var errWriterClosed = errors.New("wlog: net writer closed")
+
+// netWriter 带内存缓冲的网络写入器，每次Write的内容作为一条完整消息发送
+// 连接断开时按指数退避重连，断连时长超过spillAfter或缓冲区已满时，缓冲中的消息按顺序暂存到磁盘，重连成功后优先补发
+// 消息的先后顺序为：上次补发未完成的.replay文件、落盘文件、内存缓冲，落盘时总是先把整个内存缓冲追加到文件末尾，
+// 因此无论从哪条路径落盘，补发顺序都与写入顺序一致
+type netWriter struct {
+	cfg NetSinkConfig
+
+	notify  chan struct{} // 缓冲区有新消息时通知后台Goroutine
+	flushCh chan chan struct{}
+	closing chan struct{}
+	done    chan struct{}
+	closed  atomic.Bool
+	dropped atomic.Int64 // 缓冲区已满且未配置落盘或落盘失败时丢弃的消息数，通过DropCounter对外提供
+
+	mu           sync.Mutex
+	buf          [][]byte // 等待发送的消息，后台Goroutine发送成功后才从头部移除
+	gen          uint64   // 缓冲区整体落盘的次数，用于判断正在发送的消息是否已经被转移到磁盘
+	spillFile    *os.File
+	spillPending bool // 落盘文件中存在尚未补发的消息
+
+	// 以下字段只在后台Goroutine中访问
+	conn          net.Conn
+	backoff       time.Duration
+	nextDial      time.Time
+	outageSince   time.Time
+	replayPending bool // 存在上次补发未完成的.replay文件
+}
+
+func newNetWriter(cfg NetSinkConfig) *netWriter {
+	w := &netWriter{
+		cfg:     cfg,
+		notify:  make(chan struct{}, 1),
+		flushCh: make(chan chan struct{}),
+		closing: make(chan struct{}),
+		done:    make(chan struct{}),
+		backoff: cfg.MinBackoff,
+	}
+	// 上次进程退出时可能有未补发的消息，包括补发过程中崩溃留下的.replay文件，启动后连接成功即补发
+	if cfg.SpillPath != "" {
+		if info, err := os.Stat(cfg.SpillPath); err == nil && info.Size() > 0 {
+			w.spillPending = true
+		}
+		if _, err := os.Stat(w.replayPath()); err == nil {
+			w.replayPending = true
+		}
+	}
+	go w.run()
+	return w
+}
+
+// Write 不会阻塞调用方，缓冲区已满时缓冲中的消息连同本条消息一起按顺序落盘，未配置落盘路径时丢弃本条消息
+func (w *netWriter) Write(p []byte) (int, error) {
+	if w.closed.Load() {
+		return 0, errWriterClosed
+	}
+	msg := make([]byte, len(p)) // zap会复用p所在的缓冲区，这里必须拷贝
+	copy(msg, p)
+	w.mu.Lock()
+	if len(w.buf) < w.cfg.BufferSize {
+		w.buf = append(w.buf, msg)
+		w.mu.Unlock()
+		select {
+		case w.notify <- struct{}{}:
+		default:
+		}
+		return len(p), nil
+	}
+	if w.cfg.SpillPath == "" {
+		w.mu.Unlock()
+		w.dropped.Add(1)
+		return len(p), nil
+	}
+	w.spillBufferLocked()
+	if !w.spillLocked(msg) {
+		w.dropped.Add(1)
+	}
+	w.mu.Unlock()
+	return len(p), nil
+}
+
+// Sync 等待缓冲中的消息发送完毕，连接不可用时将其落盘，最多等待FlushTimeout
+func (w *netWriter) Sync() error {
+	if w.closed.Load() {
+		return errWriterClosed
+	}
+	ack := make(chan struct{})
+	timer := time.NewTimer(w.cfg.FlushTimeout)
+	defer timer.Stop()
+	select {
+	case w.flushCh <- ack:
+	case <-timer.C:
+		return errors.New("wlog: net writer flush timeout")
+	}
+	select {
+	case <-ack:
+		return nil
+	case <-timer.C:
+		return errors.New("wlog: net writer flush timeout")
+	}
+}
+
+func (w *netWriter) Close() error {
+	if !w.closed.CompareAndSwap(false, true) {
+		return nil
+	}
+	close(w.closing)
+	timer := time.NewTimer(w.cfg.FlushTimeout)
+	defer timer.Stop()
+	select {
+	case <-w.done:
+	case <-timer.C:
+		return errors.New("wlog: net writer close timeout")
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.spillFile != nil {
+		return w.spillFile.Close()
+	}
+	return nil
+}
+
+func (w *netWriter) run() {
+	defer close(w.done)
+	defer func() {
+		if w.conn != nil {
+			_ = w.conn.Close()
+		}
+	}()
+	for {
+		select {
+		case <-w.closing:
+			w.drain()
+			return
+		case ack := <-w.flushCh:
+			w.drain()
+			close(ack)
+			continue
+		default:
+		}
+		if msg, gen, ok := w.peek(); ok {
+			w.deliver(msg, gen, true)
+			continue
+		}
+		select {
+		case <-w.notify:
+		case ack := <-w.flushCh:
+			w.drain()
+			close(ack)
+		case <-w.closing:
+			w.drain()
+			return
+		}
+	}
+}
+
+// drain 把当前缓冲中的消息全部处理掉，期间不做退避等待，连接不可用时整体落盘
+func (w *netWriter) drain() {
+	w.mu.Lock()
+	n := len(w.buf)
+	w.mu.Unlock()
+	for ; n > 0; n-- {
+		msg, gen, ok := w.peek()
+		if !ok {
+			return
+		}
+		w.deliver(msg, gen, false)
+	}
+}
+
+func (w *netWriter) peek() ([]byte, uint64, bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if len(w.buf) == 0 {
+		return nil, w.gen, false
+	}
+	return w.buf[0], w.gen, true
+}
+
+// pop 发送成功后移除缓冲区头部的消息，期间缓冲区被整体落盘时不做任何操作
+// 这种情况下这条消息既已发送也已落盘，补发时会重复一次，但不会乱序
+func (w *netWriter) pop(gen uint64) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.gen == gen && len(w.buf) > 0 {
+		w.buf = w.buf[1:]
+	}
+}
+
+func (w *netWriter) generation() uint64 {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.gen
+}
+
+// deliver 发送缓冲区头部的消息，wait为true时在断连期间按退避间隔等待重连，直到断连时长超过SpillAfter；
+// 不再等待、收到Sync或Close时把缓冲中的全部消息按顺序落盘
+func (w *netWriter) deliver(msg []byte, gen uint64, wait bool) {
+	for {
+		if w.generation() != gen {
+			return // 消息已经随缓冲区一起被Write转移到磁盘
+		}
+		if w.conn == nil && !w.connect() {
+			if !wait || time.Since(w.outageSince) >= w.cfg.SpillAfter {
+				w.spillBuffer()
+				return
+			}
+			timer := time.NewTimer(time.Until(w.nextDial))
+			select {
+			case <-timer.C:
+				continue
+			case ack := <-w.flushCh:
+				timer.Stop()
+				w.spillBuffer()
+				close(ack)
+				return
+			case <-w.closing:
+				timer.Stop()
+				w.spillBuffer()
+				return
+			}
+		}
+		if w.generation() != gen {
+			return // 重连补发期间缓冲区被转移到磁盘，并且已经随补发一起发送
+		}
+		if err := w.writeConn(msg); err != nil {
+			w.disconnect()
+			continue
+		}
+		w.pop(gen)
+		return
+	}
+}
+
+// connect 尝试建立连接，未到下次重连时间时直接返回false，连接成功后补发磁盘上暂存的消息
+func (w *netWriter) connect() bool {
+	now := time.Now()
+	if now.Before(w.nextDial) {
+		return false
+	}
+	conn, err := net.DialTimeout(w.cfg.Network, w.cfg.Address, w.cfg.DialTimeout)
+	if err != nil {
+		if w.outageSince.IsZero() {
+			w.outageSince = now
+		}
+		w.scheduleRedial()
+		return false
+	}
+	w.conn = conn
+	w.backoff = w.cfg.MinBackoff
+	w.outageSince = time.Time{}
+	w.replaySpill()
+	return w.conn != nil
+}
+
+func (w *netWriter) disconnect() {
+	_ = w.conn.Close()
+	w.conn = nil
+	w.outageSince = time.Now()
+	w.scheduleRedial()
+}
+
+func (w *netWriter) scheduleRedial() {
+	w.nextDial = time.Now().Add(w.backoff)
+	w.backoff *= 2
+	if w.backoff > w.cfg.MaxBackoff {
+		w.backoff = w.cfg.MaxBackoff
+	}
+}
+
+func (w *netWriter) writeConn(msg []byte) error {
+	if w.cfg.WriteTimeout > 0 {
+		_ = w.conn.SetWriteDeadline(time.Now().Add(w.cfg.WriteTimeout))
+	}
+	_, err := w.conn.Write(msg)
+	return err
+}
+
+// spillBuffer 把缓冲中的消息按顺序追加到落盘文件，未配置落盘路径时丢弃
+func (w *netWriter) spillBuffer() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.spillBufferLocked()
+}
+
+func (w *netWriter) spillBufferLocked() {
+	if len(w.buf) == 0 {
+		return
+	}
+	for _, msg := range w.buf {
+		if !w.spillLocked(msg) {
+			w.dropped.Add(1)
+		}
+	}
+	w.buf = nil
+	w.gen++
+}
+
+// spillLocked 把消息追加到磁盘文件，每条消息前写入4字节的长度，未配置落盘路径或写入失败时返回false
+func (w *netWriter) spillLocked(msg []byte) bool {
+	if w.cfg.SpillPath == "" {
+		return false
+	}
+	if w.spillFile == nil {
+		f, err := os.OpenFile(w.cfg.SpillPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+		if err != nil {
+			return false
+		}
+		w.spillFile = f
+	}
+	if err := writeSpillRecord(w.spillFile, msg); err != nil {
+		return false
+	}
+	w.spillPending = true
+	return true
+}
+
+func writeSpillRecord(dst io.Writer, msg []byte) error {
+	var header [4]byte
+	binary.BigEndian.PutUint32(header[:], uint32(len(msg)))
+	_, err := dst.Write(append(header[:], msg...))
+	return err
+}
+
+func (w *netWriter) replayPath() string {
+	return w.cfg.SpillPath + ".replay"
+}
+
+// replaySpill 按写入顺序补发磁盘上暂存的消息：先补发上次未完成的.replay文件，再把落盘文件改名为.replay后补发
+// 补发期间新写入的消息进入内存缓冲，排在.replay之后；发送失败时未发送的部分保留在.replay中并断开连接
+func (w *netWriter) replaySpill() {
+	if w.replayPending {
+		if !w.sendReplayFile() {
+			return
+		}
+	}
+	w.mu.Lock()
+	if !w.spillPending {
+		w.mu.Unlock()
+		return
+	}
+	if w.spillFile != nil {
+		_ = w.spillFile.Close()
+		w.spillFile = nil
+	}
+	err := os.Rename(w.cfg.SpillPath, w.replayPath())
+	if err == nil {
+		w.spillPending = false
+		w.replayPending = true
+	}
+	w.mu.Unlock()
+	if err == nil {
+		w.sendReplayFile()
+	}
+}
+
+// sendReplayFile 发送.replay文件中的全部消息，全部发送成功后删除文件并返回true
+// 发送失败时把当前消息及之后的内容重写为新的.replay文件，保证下次从失败的位置继续
+func (w *netWriter) sendReplayFile() bool {
+	path := w.replayPath()
+	f, err := os.Open(path)
+	if err != nil {
+		w.replayPending = !errors.Is(err, os.ErrNotExist)
+		return !w.replayPending
+	}
+	reader := bufio.NewReader(f)
+	var header [4]byte
+	for {
+		if _, err = io.ReadFull(reader, header[:]); err != nil {
+			break // 文件结束，或崩溃时留下的不完整记录
+		}
+		msg := make([]byte, binary.BigEndian.Uint32(header[:]))
+		if _, err = io.ReadFull(reader, msg); err != nil {
+			break
+		}
+		if err = w.writeConn(msg); err != nil {
+			w.disconnect()
+			w.keepRemaining(path, msg, reader)
+			_ = f.Close()
+			return false
+		}
+	}
+	_ = f.Close()
+	_ = os.Remove(path)
+	w.replayPending = false
+	return true
+}
+
+// keepRemaining 用未发送的消息重写.replay文件，先写临时文件再改名，避免中途崩溃丢失消息
+func (w *netWriter) keepRemaining(path string, msg []byte, rest io.Reader) {
+	tmpPath := path + ".tmp"
+	tmp, err := os.Create(tmpPath)
+	if err != nil {
+		return // 保留原文件，下次补发时会重复已发送的部分
+	}
+	err = writeSpillRecord(tmp, msg)
+	if err == nil {
+		_, err = io.Copy(tmp, rest)
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(tmpPath)
+		return
+	}
+	_ = os.Rename(tmpPath, path)
+}
@@ -0,0 +1,74 @@
+package wlog
+
+import (
+	"errors"
+	"sync"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// Sink 是除控制台外额外挂载的日志输出目标，如syslog、日志采集端等
+type Sink interface {
+	Core() zapcore.Core
+	Close() error
+}
+
+var (
+	sinkMu sync.Mutex
+	sinks  []Sink
+)
+
+// AddSink 挂载额外的日志输出目标，挂载后每条日志会同时写入控制台和所有已挂载的Sink
+func AddSink(sink Sink) {
+	sinkMu.Lock()
+	defer sinkMu.Unlock()
+	sinks = append(sinks, sink)
+	rebuildLogger()
+}
+
+// Sync 将控制台和所有Sink中缓冲的日志刷出，一般在进程退出前调用
+func Sync() error {
+	sinkMu.Lock()
+	defer sinkMu.Unlock()
+	// 标准输出在部分终端上调用Sync会返回invalid argument错误，这里忽略控制台的Sync结果
+	_ = baseLogger.Sync()
+	var errs []error
+	for _, sink := range sinks {
+		if err := sink.Core().Sync(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Close 刷出并关闭所有已挂载的Sink，之后的日志只会输出到控制台
+func Close() error {
+	sinkMu.Lock()
+	defer sinkMu.Unlock()
+	var errs []error
+	for _, sink := range sinks {
+		if err := sink.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	sinks = nil
+	rebuildLogger()
+	return errors.Join(errs...)
+}
+
+// 调用方需持有sinkMu，已经通过Msg创建的日志条目仍使用旧的logger，不受影响
+func rebuildLogger() {
+	if len(sinks) == 0 {
+		logger.Store(baseLogger)
+		return
+	}
+	cores := make([]zapcore.Core, 0, len(sinks)+1)
+	for _, sink := range sinks {
+		cores = append(cores, sink.Core())
+	}
+	newLogger := baseLogger.WithOptions(zap.WrapCore(func(core zapcore.Core) zapcore.Core {
+		return zapcore.NewTee(append([]zapcore.Core{core}, cores...)...)
+	}))
+	logger.Store(newLogger)
+}
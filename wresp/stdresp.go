@@ -31,6 +31,16 @@ func (e *errorCode) Error() string {
 	return fmt.Sprintf("错误码: %d，错误原因: %s", e.code, e.message)
 }
 
+// ErrorCode 返回业务错误码，wlog的Err()会通过该方法将错误码作为单独字段输出
+func (e *errorCode) ErrorCode() int {
+	return e.code
+}
+
+// HTTPStatus 返回该错误码对应的HTTP状态码
+func (e *errorCode) HTTPStatus() int {
+	return e.httpStatus
+}
+
 func NewErrorCode(code int, message string) error {
 	return NewErrorCodeWithStatus(code, message, http.StatusInternalServerError) // 默认设置HTTP状态码500
 }